@@ -130,11 +130,10 @@ func main() {
 
 	// ===== Sprint for logging =====
 	fmt.Println("\n=== Sprint ===")
-	pf.DefaultConfig.ColorMode = false
 	s := pf.Sprint(map[string]interface{}{
 		"action": "login",
 		"user":   "jsmith",
 		"ok":     true,
-	})
+	}, pf.WithColor(false))
 	fmt.Printf("[LOG] request: %s\n", s)
 }
@@ -0,0 +1,628 @@
+package pf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPathNotFound is returned when a path does not exist in the target.
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTypeMismatch is returned when a path or value does not fit the
+	// type found in the target.
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrTestFailed is returned when a JSON Patch "test" operation fails.
+	ErrTestFailed = errors.New("test failed")
+)
+
+// PathError records a failure to apply a change at a path.
+type PathError struct {
+	// Op is the change kind or JSON Patch operation.
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return "pf: " + e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// Apply applies changes, as returned by Changes, to the value target
+// points to. Nil pointers, maps and slices along a path are allocated as
+// needed. Removals are applied after other changes, from the highest
+// index down, so that removing slice elements does not shift the indices
+// of later changes. Failures are reported as *PathError.
+func Apply(target interface{}, changes []Change) error {
+	root, err := targetValue(target)
+	if err != nil {
+		return err
+	}
+	return applyChanges(root, changes)
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON Patch document to the value
+// target points to. Pointer segments are matched against json tag names,
+// then Go field names. Failures are reported as *PathError.
+func ApplyJSONPatch(target interface{}, patch []byte) error {
+	root, err := targetValue(target)
+	if err != nil {
+		return err
+	}
+	var ops []JSONPatchOp
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return fmt.Errorf("pf: invalid JSON Patch: %w", err)
+	}
+	for _, op := range ops {
+		if err := applyJSONPatchOp(root, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func targetValue(target interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return reflect.Value{}, fmt.Errorf("pf: apply target must be a non-nil pointer, got %T", target)
+	}
+	return v.Elem(), nil
+}
+
+// applyChanges applies changes to the settable value root. Element
+// removals are applied last and from the highest index down, so that
+// removing a slice element does not shift the indices of later changes.
+func applyChanges(root reflect.Value, changes []Change) error {
+	ordered := append([]Change(nil), changes...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		ri, rj := ordered[i].Kind == Removed, ordered[j].Kind == Removed
+		if ri != rj {
+			return rj
+		}
+		if ri {
+			return comparePaths(ordered[i].Path, ordered[j].Path) > 0
+		}
+		return false
+	})
+	for _, ch := range ordered {
+		if err := setPath(root, ch.Path, ch.Kind, ch.New); err != nil {
+			return &PathError{Op: ch.Kind.String(), Path: ch.Path.String(), Err: err}
+		}
+	}
+	return nil
+}
+
+func applyJSONPatchOp(root reflect.Value, op JSONPatchOp) error {
+	fail := func(err error) error {
+		return &PathError{Op: op.Op, Path: op.Path, Err: err}
+	}
+	path, err := resolvePointer(root, op.Path)
+	if err != nil {
+		return fail(err)
+	}
+
+	switch op.Op {
+	case "add":
+		err = setPath(root, path, Added, op.Value)
+	case "replace":
+		if _, err = getPath(root, path); err == nil {
+			err = setPath(root, path, Modified, op.Value)
+		}
+	case "remove":
+		if _, err = getPath(root, path); err == nil {
+			err = setPath(root, path, Removed, nil)
+		}
+	case "test":
+		var cur reflect.Value
+		if cur, err = getPath(root, path); err == nil {
+			err = testJSONValue(cur, op.Value)
+		}
+	case "move", "copy":
+		var from Path
+		if from, err = resolvePointer(root, op.From); err != nil {
+			return &PathError{Op: op.Op, Path: op.From, Err: err}
+		}
+		var cur reflect.Value
+		if cur, err = getPath(root, from); err != nil {
+			return &PathError{Op: op.Op, Path: op.From, Err: err}
+		}
+		val := interfaceOf(deepCopy(cur))
+		if op.Op == "move" {
+			if err = setPath(root, from, Removed, nil); err != nil {
+				return &PathError{Op: op.Op, Path: op.From, Err: err}
+			}
+			// Removing from may have shifted the target index.
+			if path, err = resolvePointer(root, op.Path); err != nil {
+				return fail(err)
+			}
+		}
+		err = setPath(root, path, Added, val)
+	default:
+		err = fmt.Errorf("unknown operation %q", op.Op)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+func testJSONValue(cur reflect.Value, want json.RawMessage) error {
+	got, err := toJSONValue(interfaceOf(cur))
+	if err != nil {
+		return err
+	}
+	var w interface{}
+	dec := json.NewDecoder(strings.NewReader(string(want)))
+	dec.UseNumber()
+	if err := dec.Decode(&w); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(got, w) {
+		return ErrTestFailed
+	}
+	return nil
+}
+
+// resolvePointer converts an RFC 6901 JSON Pointer into a Path over v,
+// matching struct fields by json tag name or Go field name. A final "-"
+// segment on a slice addresses the position after the last element.
+func resolvePointer(v reflect.Value, ptr string) (Path, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("%w: invalid JSON Pointer %q", ErrPathNotFound, ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+
+	var path Path
+	for i, tok := range tokens {
+		tok = unescape.Replace(tok)
+		for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+			switch {
+			case !v.IsNil():
+				v = v.Elem()
+			case v.Kind() == reflect.Ptr:
+				v = reflect.Zero(v.Type().Elem())
+			default:
+				v = reflect.Value{}
+			}
+		}
+		if !v.IsValid() {
+			return nil, ErrPathNotFound
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, pos, ok := jsonField(v.Type(), tok)
+			if !ok {
+				return nil, fmt.Errorf("%w: no field %q in %s", ErrPathNotFound, tok, v.Type())
+			}
+			sf := v.Type().FieldByIndex(field.index)
+			path = append(path, PathElem{Kind: FieldElem, Field: sf.Name, Name: tok, Index: pos})
+			if f, ok := fieldByIndex(v, field.index, false); ok {
+				v = f
+			} else {
+				v = reflect.Zero(sf.Type)
+			}
+		case reflect.Map:
+			key, err := parseMapKey(tok, v.Type().Key())
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, PathElem{Kind: KeyElem, Key: key.Interface()})
+			if next := v.MapIndex(key); next.IsValid() {
+				v = next
+			} else {
+				v = reflect.Zero(v.Type().Elem())
+			}
+		case reflect.Slice, reflect.Array:
+			idx := v.Len()
+			if tok != "-" || i != len(tokens)-1 {
+				n, err := strconv.Atoi(tok)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("%w: invalid index %q", ErrPathNotFound, tok)
+				}
+				idx = n
+			}
+			// Only a final segment may address the end, to append;
+			// RFC 6902 forbids indexes past it.
+			if idx > v.Len() || idx == v.Len() && i != len(tokens)-1 {
+				return nil, fmt.Errorf("%w: index %d out of range", ErrPathNotFound, idx)
+			}
+			path = append(path, PathElem{Kind: IndexElem, Index: idx})
+			if idx < v.Len() {
+				v = v.Index(idx)
+			} else {
+				v = reflect.Zero(v.Type().Elem())
+			}
+		default:
+			return nil, fmt.Errorf("%w: cannot index %s", ErrTypeMismatch, v.Type())
+		}
+	}
+	return path, nil
+}
+
+// jsonField finds the field of struct type t that a JSON Pointer segment
+// or object key names, as encoding/json does: by json name, or else the
+// first field whose json name matches without regard to case. Fields of
+// embedded structs are promoted. As a pf extension, for paths printed
+// without json tags, the Go field name matches when no json name does.
+// pos is the field's position among the fields searched.
+func jsonField(t reflect.Type, name string) (field structField, pos int, ok bool) {
+	fields := Config{UseJSONTags: true}.structFields(t)
+	for i, f := range fields {
+		if f.name == name {
+			return f, i, true
+		}
+	}
+	for i, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, i, true
+		}
+	}
+	for i, f := range fields {
+		if t.FieldByIndex(f.index).Name == name {
+			return f, i, true
+		}
+	}
+	return structField{}, 0, false
+}
+
+// goField finds the exported field of struct type t with the Go name
+// name, directly in t or promoted from an embedded struct.
+func goField(t reflect.Type, name string) ([]int, bool) {
+	if sf, ok := t.FieldByName(name); ok && sf.IsExported() {
+		return sf.Index, true
+	}
+	// A field that is ambiguous in Go may still be promoted by json tags.
+	for _, f := range (Config{UseJSONTags: true}).structFields(t) {
+		if t.FieldByIndex(f.index).Name == name {
+			return f.index, true
+		}
+	}
+	return nil, false
+}
+
+// fieldByIndex returns the field of struct v at index. A nil embedded
+// pointer on the way is allocated if alloc is set, and otherwise makes
+// the field missing.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc || !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func parseMapKey(tok string, t reflect.Type) (reflect.Value, error) {
+	k := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		k.SetString(tok)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(tok, 10, t.Bits())
+		if err != nil {
+			return k, fmt.Errorf("%w: invalid %s key %q", ErrTypeMismatch, t, tok)
+		}
+		k.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(tok, 10, t.Bits())
+		if err != nil {
+			return k, fmt.Errorf("%w: invalid %s key %q", ErrTypeMismatch, t, tok)
+		}
+		k.SetUint(n)
+	default:
+		return k, fmt.Errorf("%w: unsupported map key type %s", ErrTypeMismatch, t)
+	}
+	return k, nil
+}
+
+// getPath returns the value at path beneath v.
+func getPath(v reflect.Value, path Path) (reflect.Value, error) {
+	for _, step := range path {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, ErrPathNotFound
+			}
+			v = v.Elem()
+		}
+		switch step.Kind {
+		case FieldElem:
+			f, err := findField(v, step, false)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v = f
+		case KeyElem:
+			if v.Kind() != reflect.Map {
+				return reflect.Value{}, fmt.Errorf("%w: cannot index %s by key", ErrTypeMismatch, v.Type())
+			}
+			key, err := convertValue(step.Key, v.Type().Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if v = v.MapIndex(key); !v.IsValid() {
+				return reflect.Value{}, ErrPathNotFound
+			}
+		case IndexElem:
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return reflect.Value{}, fmt.Errorf("%w: cannot index %s", ErrTypeMismatch, v.Type())
+			}
+			if step.Index < 0 || step.Index >= v.Len() {
+				return reflect.Value{}, fmt.Errorf("%w: index %d out of range", ErrPathNotFound, step.Index)
+			}
+			v = v.Index(step.Index)
+		}
+	}
+	return v, nil
+}
+
+// findField returns the field of struct v that step selects, by Go
+// field name, or by display name when the Go name is not recorded.
+// Promoted fields are found too; alloc allocates nil embedded pointers
+// on the way to them.
+func findField(v reflect.Value, step PathElem, alloc bool) (reflect.Value, error) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%w: cannot select field of %s", ErrTypeMismatch, v.Type())
+	}
+	name := step.Field
+	index, ok := goField(v.Type(), name)
+	if name == "" {
+		var field structField
+		field, _, ok = jsonField(v.Type(), step.Name)
+		name, index = step.Name, field.index
+	}
+	var f reflect.Value
+	if ok {
+		f, ok = fieldByIndex(v, index, alloc)
+	}
+	if !ok || !f.CanInterface() {
+		return reflect.Value{}, fmt.Errorf("%w: no field %q in %s", ErrPathNotFound, name, v.Type())
+	}
+	return f, nil
+}
+
+// setPath applies a single change at path beneath the settable value v,
+// allocating nil pointers, maps and slices along the way. Adding at a
+// slice index inserts before the element at that index.
+func setPath(v reflect.Value, path Path, kind ChangeKind, value interface{}) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if len(path) == 0 {
+			return assign(v, kind, value)
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setPath(v.Elem(), path, kind, value)
+	case reflect.Interface:
+		if len(path) == 0 || v.IsNil() {
+			return assign(v, kind, value)
+		}
+		// Interface contents are not addressable: copy, modify, store.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := setPath(elem, path, kind, value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if len(path) == 0 {
+		return assign(v, kind, value)
+	}
+
+	step, rest := path[0], path[1:]
+	last := len(rest) == 0
+	switch step.Kind {
+	case FieldElem:
+		f, err := findField(v, step, true)
+		if err != nil {
+			return err
+		}
+		return setPath(f, rest, kind, value)
+
+	case KeyElem:
+		if v.Kind() != reflect.Map {
+			return fmt.Errorf("%w: cannot index %s by key", ErrTypeMismatch, v.Type())
+		}
+		key, err := convertValue(step.Key, v.Type().Key())
+		if err != nil {
+			return err
+		}
+		if last && kind == Removed {
+			if !v.MapIndex(key).IsValid() {
+				return ErrPathNotFound
+			}
+			v.SetMapIndex(key, reflect.Value{})
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		// Map elements are not addressable: copy, modify, store.
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if cur := v.MapIndex(key); cur.IsValid() {
+			elem.Set(cur)
+		}
+		if err := setPath(elem, rest, kind, value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+
+	case IndexElem:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("%w: cannot index %s", ErrTypeMismatch, v.Type())
+		}
+		i := step.Index
+		if i < 0 {
+			return fmt.Errorf("%w: negative index %d", ErrPathNotFound, i)
+		}
+		if v.Kind() == reflect.Array {
+			if i >= v.Len() {
+				return fmt.Errorf("%w: index %d out of range for %s", ErrPathNotFound, i, v.Type())
+			}
+			if last && kind == Removed {
+				v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+				return nil
+			}
+			return setPath(v.Index(i), rest, kind, value)
+		}
+		switch {
+		case last && kind == Removed:
+			if i >= v.Len() {
+				return fmt.Errorf("%w: index %d out of range", ErrPathNotFound, i)
+			}
+			reflect.Copy(v.Slice(i, v.Len()), v.Slice(i+1, v.Len()))
+			v.Index(v.Len() - 1).Set(reflect.Zero(v.Type().Elem()))
+			v.SetLen(v.Len() - 1)
+			return nil
+		case last && kind == Added && i < v.Len():
+			// Insert: grow by one and shift the tail right.
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+			reflect.Copy(v.Slice(i+1, v.Len()), v.Slice(i, v.Len()-1))
+			v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+		case i >= v.Len():
+			v.Set(reflect.AppendSlice(v, reflect.MakeSlice(v.Type(), i+1-v.Len(), i+1-v.Len())))
+		}
+		return setPath(v.Index(i), rest, kind, value)
+	}
+	return fmt.Errorf("%w: invalid path element", ErrPathNotFound)
+}
+
+// assign stores value into v, or the zero value when removing.
+func assign(v reflect.Value, kind ChangeKind, value interface{}) error {
+	if kind == Removed {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	rv, err := convertValue(value, v.Type())
+	if err != nil {
+		return err
+	}
+	v.Set(rv)
+	return nil
+}
+
+// convertValue converts x to type t. Values that cannot be converted
+// directly, such as generic JSON values or json.RawMessage, are
+// converted through their JSON encoding.
+func convertValue(x interface{}, t reflect.Type) (reflect.Value, error) {
+	if raw, ok := x.(json.RawMessage); ok && t != reflect.TypeOf(raw) {
+		return decodeJSONAs(raw, t)
+	}
+	if x == nil {
+		return reflect.Zero(t), nil
+	}
+	rv := reflect.ValueOf(x)
+	switch {
+	case rv.Type().AssignableTo(t):
+		return rv, nil
+	case rv.Type().ConvertibleTo(t) && convertible(rv.Kind(), t.Kind()):
+		return rv.Convert(t), nil
+	}
+	data, err := json.Marshal(x)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("%w: cannot use %s as %s", ErrTypeMismatch, rv.Type(), t)
+	}
+	return decodeJSONAs(data, t)
+}
+
+func decodeJSONAs(data []byte, t reflect.Type) (reflect.Value, error) {
+	p := reflect.New(t)
+	if err := json.Unmarshal(data, p.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("%w: cannot use %s as %s", ErrTypeMismatch, data, t)
+	}
+	return p.Elem(), nil
+}
+
+// convertible limits reflect conversions to those that preserve meaning,
+// e.g. excluding int-to-string.
+func convertible(from, to reflect.Kind) bool {
+	isNum := func(k reflect.Kind) bool {
+		return k >= reflect.Int && k <= reflect.Float64
+	}
+	if isNum(from) || isNum(to) {
+		return isNum(from) && isNum(to)
+	}
+	return true
+}
+
+// deepCopy returns a copy of v that shares no maps, slices or pointers
+// with it. Unexported struct fields are copied shallowly.
+func deepCopy(v reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(deepCopy(v.Elem()))
+		return p
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return c
+	}
+	return v
+}
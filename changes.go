@@ -0,0 +1,358 @@
+package pf
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ChangeKind describes how a value at a path differs between two versions.
+type ChangeKind int
+
+const (
+	// Modified means the value exists in both versions but differs.
+	Modified ChangeKind = iota + 1
+	// Added means the value exists only in the new version.
+	Added
+	// Removed means the value exists only in the old version.
+	Removed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Modified:
+		return "modified"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// PathKind identifies the kind of step a PathElem takes.
+type PathKind int
+
+const (
+	// FieldElem selects a struct field.
+	FieldElem PathKind = iota + 1
+	// KeyElem selects a map entry.
+	KeyElem
+	// IndexElem selects a slice or array element.
+	IndexElem
+)
+
+// PathElem is one step from a value to a nested value.
+type PathElem struct {
+	Kind PathKind
+	// Field is the Go name of the struct field (FieldElem).
+	Field string
+	// Name is the display name of the struct field: the json tag name
+	// when Config.UseJSONTags is set, otherwise the Go name (FieldElem).
+	Name string
+	// Key is the map key (KeyElem).
+	Key interface{}
+	// Index is the element index (IndexElem), or the position of the
+	// field among the printed fields of its struct (FieldElem), which
+	// may be promoted from an embedded struct.
+	Index int
+}
+
+// Path locates a nested value, e.g. Orders[1].Amount or Meta["role"].
+type Path []PathElem
+
+// String renders the path in Go-like syntax.
+func (p Path) String() string {
+	var sb strings.Builder
+	for i, e := range p {
+		switch e.Kind {
+		case FieldElem:
+			if i > 0 {
+				sb.WriteString(".")
+			}
+			if e.Name != "" {
+				sb.WriteString(e.Name)
+			} else {
+				sb.WriteString(e.Field)
+			}
+		case KeyElem:
+			if s, ok := e.Key.(string); ok {
+				sb.WriteString("[" + strconv.Quote(s) + "]")
+			} else {
+				sb.WriteString(fmt.Sprintf("[%v]", e.Key))
+			}
+		case IndexElem:
+			sb.WriteString("[" + strconv.Itoa(e.Index) + "]")
+		}
+	}
+	return sb.String()
+}
+
+// Change is a single difference between two values.
+type Change struct {
+	Path Path
+	Kind ChangeKind
+	// Old is the value before the change (nil when Added).
+	Old interface{}
+	// New is the value after the change (nil when Removed).
+	New interface{}
+}
+
+// Changes returns the list of differences between a and b using this
+// config, in field/key/index order. Structs, maps, slices and arrays are
+// compared element by element; other values are compared by their
+// pretty-printed form, like the differ. Values of different types, such
+// as 1 and int64(1) or a nil pointer and nil, are always Modified.
+func (c Config) Changes(a, b interface{}) []Change {
+	w := &changeWalker{config: c}
+	w.walk(nil, reflect.ValueOf(a), reflect.ValueOf(b))
+	return w.changes
+}
+
+// Equal reports whether a and b have no differences using this config.
+// It stops at the first difference found.
+func (c Config) Equal(a, b interface{}) bool {
+	w := &changeWalker{config: c, limit: 1}
+	w.walk(nil, reflect.ValueOf(a), reflect.ValueOf(b))
+	return len(w.changes) == 0
+}
+
+type changeWalker struct {
+	config  Config
+	changes []Change
+	// limit stops the walk after this many changes (0 = unlimited).
+	limit int
+}
+
+func (w *changeWalker) add(path Path, kind ChangeKind, a, b reflect.Value) {
+	ch := Change{Path: append(Path(nil), path...), Kind: kind}
+	if kind != Added {
+		ch.Old = interfaceOf(a)
+	}
+	if kind != Removed {
+		ch.New = interfaceOf(b)
+	}
+	w.changes = append(w.changes, ch)
+}
+
+func (w *changeWalker) walk(path Path, a, b reflect.Value) {
+	if w.limit > 0 && len(w.changes) >= w.limit {
+		return
+	}
+	if !sameType(a, b) {
+		w.add(path, Modified, a, b)
+		return
+	}
+	oa, ob := a, b
+	a, b = unwrapValue(a), unwrapValue(b)
+
+	if !a.IsValid() {
+		return
+	}
+	if isLeaf(a) {
+		if w.render(a) != w.render(b) {
+			w.add(path, Modified, oa, ob)
+		}
+		return
+	}
+
+	switch a.Kind() {
+	case reflect.Struct:
+		t := a.Type()
+		for pos, field := range w.config.structFields(t) {
+			elem := fieldChild(t, pos, field, reflect.Value{}, w.config).elem
+			// Like diffs, leave out fields promoted through a nil
+			// pointer or, with json tags, empty ones.
+			af, aErr := a.FieldByIndexErr(field.index)
+			bf, bErr := b.FieldByIndexErr(field.index)
+			aMissing := aErr != nil || field.omitted(af)
+			bMissing := bErr != nil || field.omitted(bf)
+			switch {
+			case aMissing && bMissing:
+			case aMissing:
+				w.add(append(path, elem), Added, reflect.Value{}, bf)
+			case bMissing:
+				w.add(append(path, elem), Removed, af, reflect.Value{})
+			default:
+				w.walk(append(path, elem), af, bf)
+			}
+		}
+	case reflect.Map:
+		if a.IsNil() != b.IsNil() {
+			w.add(path, Modified, oa, ob)
+			return
+		}
+		keys := make(map[string]reflect.Value)
+		for _, k := range a.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, k := range b.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		for _, k := range sortMapKeys(collectValues(keys)) {
+			elem := PathElem{Kind: KeyElem, Key: k.Interface()}
+			av, bv := a.MapIndex(k), b.MapIndex(k)
+			switch {
+			case !bv.IsValid():
+				w.add(append(path, elem), Removed, av, bv)
+			case !av.IsValid():
+				w.add(append(path, elem), Added, av, bv)
+			default:
+				w.walk(append(path, elem), av, bv)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if a.Kind() == reflect.Slice && a.IsNil() != b.IsNil() {
+			w.add(path, Modified, oa, ob)
+			return
+		}
+		n := a.Len()
+		if b.Len() > n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			elem := PathElem{Kind: IndexElem, Index: i}
+			switch {
+			case i >= b.Len():
+				w.add(append(path, elem), Removed, a.Index(i), reflect.Value{})
+			case i >= a.Len():
+				w.add(append(path, elem), Added, reflect.Value{}, b.Index(i))
+			default:
+				w.walk(append(path, elem), a.Index(i), b.Index(i))
+			}
+		}
+	}
+}
+
+func (w *changeWalker) render(v reflect.Value) string {
+	noColor := w.config
+	noColor.ColorMode = false
+	noColor.MaxDepth = 0
+	noColor.Rules = nil
+	return noColor.Sprint(interfaceOf(v))
+}
+
+// unwrapValue dereferences non-nil pointers and interfaces. Nil pointers
+// and interfaces become the invalid Value.
+func unwrapValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		if v.Kind() == reflect.Ptr && hasPrinter(v) {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isLeaf reports whether v is compared as a whole rather than element
+// by element: scalars, and values that format themselves.
+func isLeaf(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return hasPrinter(v)
+	}
+	return true
+}
+
+// hasPrinter reports whether the formatter would render v through one
+// of the interfaces checked by tryInterfaces.
+func hasPrinter(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return false
+	}
+	t := v.Type()
+	ppc := reflect.TypeOf((*PrettyPrinterConfig)(nil)).Elem()
+	pp := reflect.TypeOf((*PrettyPrinter)(nil)).Elem()
+	if t.Implements(ppc) || t.Implements(pp) {
+		return true
+	}
+	if t.Kind() != reflect.Ptr && (reflect.PointerTo(t).Implements(ppc) || reflect.PointerTo(t).Implements(pp)) {
+		return true
+	}
+	logValuer := reflect.TypeOf((*slog.LogValuer)(nil)).Elem()
+	if t.Implements(logValuer) || t == reflect.TypeOf(slog.Value{}) {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		stringer := reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+		errType := reflect.TypeOf((*error)(nil)).Elem()
+		return t.Implements(stringer) || t.Implements(errType)
+	}
+	return false
+}
+
+// sameType reports whether a and b have the same type once pointers
+// and interfaces are unwrapped, like the differ's type check. A nil
+// pointer keeps its type, so it differs from an untyped nil.
+func sameType(a, b reflect.Value) bool {
+	ua, ub := unwrapValue(a), unwrapValue(b)
+	if ua.IsValid() && ub.IsValid() {
+		return ua.Type() == ub.Type()
+	}
+	return ua.IsValid() == ub.IsValid() && dynamicType(a) == dynamicType(b)
+}
+
+// dynamicType returns the type of the value held by v, looking through
+// interfaces, or nil for an untyped nil.
+func dynamicType(v reflect.Value) reflect.Type {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() == reflect.Interface {
+		return nil
+	}
+	return v.Type()
+}
+
+// typeName returns the name of the type of the value held by v, or
+// "nil" for an untyped nil.
+func typeName(v reflect.Value) string {
+	if t := dynamicType(v); t != nil {
+		return t.String()
+	}
+	return "nil"
+}
+
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// comparePaths orders paths the way the change walk visits them:
+// struct fields by declaration, map keys by string form, indices
+// numerically, parents before children.
+func comparePaths(a, b Path) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ea, eb := a[i], b[i]
+		if ea.Kind != eb.Kind {
+			return int(ea.Kind) - int(eb.Kind)
+		}
+		switch ea.Kind {
+		case FieldElem, IndexElem:
+			if ea.Index != eb.Index {
+				return ea.Index - eb.Index
+			}
+		case KeyElem:
+			ka, kb := fmt.Sprint(ea.Key), fmt.Sprint(eb.Key)
+			if ka != kb {
+				return strings.Compare(ka, kb)
+			}
+		}
+	}
+	return len(a) - len(b)
+}
+
+// hasPathPrefix reports whether prefix is a proper or equal prefix of p.
+func hasPathPrefix(p, prefix Path) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	return comparePaths(p[:len(prefix)], prefix) == 0
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatYAML   = "yaml"
+)
+
+// readInputs decodes every document in the named files, or in stdin
+// when no files are given.
+func readInputs(paths []string, stdin io.Reader, format string) ([]interface{}, error) {
+	if len(paths) == 0 {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		return decodeDocuments("<stdin>", data, format)
+	}
+	var docs []interface{}
+	for _, path := range paths {
+		d, err := readFile(path, stdin, format)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d...)
+	}
+	return docs, nil
+}
+
+// readFile decodes every document in the named file ("-" is stdin).
+func readFile(path string, stdin io.Reader, format string) ([]interface{}, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeDocuments(path, data, format)
+}
+
+// decodeDocuments decodes data into generic values: maps, slices,
+// strings, bools, nil, int64 and float64. An empty input has no
+// documents.
+func decodeDocuments(name string, data []byte, format string) ([]interface{}, error) {
+	if format == "" || format == "auto" {
+		format = detectFormat(name, data)
+	}
+	var docs []interface{}
+	var err error
+	switch format {
+	case formatJSON, formatNDJSON:
+		docs, err = decodeJSON(data)
+	case formatYAML:
+		docs, err = decodeYAML(data)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return docs, nil
+}
+
+// detectFormat picks the format from the file extension, falling back
+// to JSON for input that looks like JSON and YAML otherwise.
+func detectFormat(name string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return formatJSON
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".yaml", ".yml":
+		return formatYAML
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[' || trimmed[0] == '"') {
+		return formatJSON
+	}
+	return formatYAML
+}
+
+// decodeJSON decodes a stream of JSON values. A single document and
+// newline-delimited JSON are both streams.
+func decodeJSON(data []byte) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var docs []interface{}
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, normalizeNumbers(v))
+	}
+}
+
+// normalizeNumbers replaces json.Number values with int64 when they are
+// integers that fit and float64 otherwise, so they print as numbers.
+// Numbers out of float64 range are kept as their literal text.
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalizeNumbers(e)
+		}
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalizeNumbers(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// runDiff implements "pf diff old new". It decodes both files, drops the
+// ignored paths, matches array elements by key fields when asked, and
+// prints a pf diff. Like diff(1), it exits with 0 when the documents are
+// equal, 1 when they differ and 2 on errors.
+//
+//	pf diff -ignore metadata.resourceVersion -key name deployed.yaml local.yaml
+func runDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pf diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: pf diff [flags] old new")
+		fs.PrintDefaults()
+	}
+	opts := addOutputFlags(fs)
+	var ignores, keys stringList
+	fs.Var(&ignores, "ignore", "path to leave out of the comparison, e.g. metadata.uid, items[*].id or /a/b (repeatable)")
+	fs.Var(&keys, "key", "match array elements by this object field instead of by index (repeatable)")
+	context := fs.Int("context", 3, "unchanged lines shown around each change (0 = all)")
+	summary := fs.Bool("summary", false, "print a one-line summary of the changes first")
+	quiet := fs.Bool("q", false, "print nothing; only set the exit code")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	config, err := opts.config(stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "pf: %v\n", err)
+		return 2
+	}
+	if *context < 0 {
+		fmt.Fprintf(stderr, "pf: invalid -context %d\n", *context)
+		return 2
+	}
+	config.DiffContext = *context
+	config.DiffSummary = *summary
+	config.OldLabel, config.NewLabel = fs.Arg(0), fs.Arg(1)
+
+	var paths [][]pathStep
+	for _, s := range ignores {
+		p, err := parsePath(s)
+		if err != nil {
+			fmt.Fprintf(stderr, "pf: invalid -ignore %q: %v\n", s, err)
+			return 2
+		}
+		paths = append(paths, p)
+	}
+
+	var docs [2]interface{}
+	for i, name := range fs.Args() {
+		d, err := readFile(name, stdin, opts.format)
+		if err != nil {
+			fmt.Fprintf(stderr, "pf: %v\n", err)
+			return 2
+		}
+		// A stream of documents is compared as a list, and an empty
+		// file as null.
+		var doc interface{}
+		switch len(d) {
+		case 0:
+		case 1:
+			doc = d[0]
+		default:
+			doc = d
+		}
+		for _, p := range paths {
+			doc = removePath(doc, p)
+		}
+		docs[i] = keyArrays(doc, keys)
+	}
+
+	if config.Equal(docs[0], docs[1]) {
+		return 0
+	}
+	if !*quiet {
+		fmt.Fprintln(stdout, config.SprintDiff(docs[0], docs[1]))
+	}
+	return 1
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// pathStep is one step of an -ignore path: an object key or array
+// index, or any of them when wildcard is set.
+type pathStep struct {
+	key      string
+	wildcard bool
+}
+
+func (s pathStep) matches(key string) bool {
+	return s.wildcard || s.key == key
+}
+
+// parsePath parses a dotted path such as spec.containers[0].image,
+// items[*].id or meta["a.b"], or a JSON Pointer such as /spec/replicas.
+// "*" matches any key or index.
+func parsePath(s string) ([]pathStep, error) {
+	if strings.HasPrefix(s, "/") {
+		var steps []pathStep
+		for _, seg := range strings.Split(s[1:], "/") {
+			seg = strings.NewReplacer("~1", "/", "~0", "~").Replace(seg)
+			steps = append(steps, pathStep{key: seg, wildcard: seg == "*"})
+		}
+		return steps, nil
+	}
+
+	var steps []pathStep
+	rest := strings.TrimPrefix(s, ".")
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "[\""):
+			n, key, err := scanQuoted(rest[1:])
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(rest[1+n:], "]") {
+				return nil, fmt.Errorf("missing ] after %s", rest[1:1+n])
+			}
+			steps = append(steps, pathStep{key: key})
+			rest = rest[n+2:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ]")
+			}
+			idx := rest[1:end]
+			if _, err := strconv.Atoi(idx); err != nil && idx != "*" {
+				return nil, fmt.Errorf("invalid index %q", idx)
+			}
+			steps = append(steps, pathStep{key: idx, wildcard: idx == "*"})
+			rest = rest[end+1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key")
+			}
+			key := rest[:end]
+			steps = append(steps, pathStep{key: key, wildcard: key == "*"})
+			rest = rest[end:]
+		}
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, fmt.Errorf("trailing .")
+			}
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return steps, nil
+}
+
+// removePath deletes every value matching path from v.
+func removePath(v interface{}, path []pathStep) interface{} {
+	step, rest := path[0], path[1:]
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if !step.matches(k) {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, k)
+			} else {
+				v[k] = removePath(e, rest)
+			}
+		}
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for i, e := range v {
+			switch {
+			case !step.matches(strconv.Itoa(i)):
+				out = append(out, e)
+			case len(rest) > 0:
+				out = append(out, removePath(e, rest))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// keyArrays replaces arrays of objects that all have a unique scalar
+// value for one of the key fields with an object keyed by "field=value",
+// so that elements are matched by identity rather than position.
+func keyArrays(v interface{}, keys []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = keyArrays(e, keys)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = keyArrays(e, keys)
+		}
+		for _, key := range keys {
+			if m, ok := keyByField(v, key); ok {
+				return m
+			}
+		}
+	}
+	return v
+}
+
+func keyByField(elems []interface{}, key string) (map[string]interface{}, bool) {
+	if len(elems) == 0 {
+		return nil, false
+	}
+	m := make(map[string]interface{}, len(elems))
+	for _, e := range elems {
+		obj, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		id, ok := obj[key]
+		if !ok {
+			return nil, false
+		}
+		switch id.(type) {
+		case string, int64, float64, bool:
+		default:
+			return nil, false
+		}
+		name := key + "=" + fmt.Sprint(id)
+		if _, dup := m[name]; dup {
+			return nil, false
+		}
+		m[name] = obj
+	}
+	return m, true
+}
@@ -0,0 +1,181 @@
+// Command pf pretty-prints JSON, NDJSON and YAML documents the same way
+// pf.Print prints Go values.
+//
+// Usage:
+//
+//	pf [flags] [file ...]
+//	pf diff [flags] old new
+//
+// With no files, pf reads standard input. The input format is taken from
+// the file extension (.json, .ndjson, .jsonl, .yaml, .yml) or detected
+// from the content, and can be forced with -format. Every document in
+// the input is printed in turn; map keys are printed in sorted order.
+//
+//	curl -s https://api.example.com/users/1 | pf -depth 2
+//	pf -max-string 40 -max-items 5 events.ndjson
+//
+// The diff subcommand compares two files structurally; see runDiff.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nd-forge/pf"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns its exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "diff" {
+		return runDiff(args[1:], stdin, stdout, stderr)
+	}
+
+	fs := flag.NewFlagSet("pf", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: pf [flags] [file ...]\n       pf diff [flags] old new")
+		fs.PrintDefaults()
+	}
+	opts := addOutputFlags(fs)
+	fs.IntVar(&opts.maxString, "max-string", 0, "truncate strings longer than this many characters (0 = off)")
+	fs.IntVar(&opts.maxItems, "max-items", 0, "truncate arrays longer than this many elements (0 = off)")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	config, err := opts.config(stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "pf: %v\n", err)
+		return 2
+	}
+	docs, err := readInputs(fs.Args(), stdin, opts.format)
+	if err != nil {
+		fmt.Fprintf(stderr, "pf: %v\n", err)
+		return 1
+	}
+	for _, doc := range docs {
+		config.Fprint(stdout, opts.truncate(doc))
+	}
+	return 0
+}
+
+// outputOptions are the formatting flags. All subcommands share the
+// ones registered by addOutputFlags.
+type outputOptions struct {
+	fs        *flag.FlagSet
+	color     string
+	indent    int
+	depth     int
+	width     int
+	maxString int
+	maxItems  int
+	format    string
+	tree      bool
+	mask      bool
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputOptions {
+	o := &outputOptions{fs: fs}
+	fs.StringVar(&o.color, "color", "auto", "colorize output: auto, always or never")
+	fs.IntVar(&o.indent, "indent", 2, "spaces per indent level")
+	fs.IntVar(&o.depth, "depth", 0, "maximum nesting depth (0 = unlimited)")
+	fs.IntVar(&o.width, "width", 0, "maximum line width; values that fit are printed on one line (0 = off)")
+	fs.StringVar(&o.format, "format", "auto", "input format: auto, json, ndjson or yaml")
+	fs.BoolVar(&o.tree, "tree", false, "draw nested values as a tree")
+	fs.BoolVar(&o.mask, "mask", false, "mask values that look like secrets")
+	return o
+}
+
+// config builds the pf config for output written to w. It starts from
+// pf.Default, so the PF_* environment variables apply unless overridden
+// by a flag.
+func (o *outputOptions) config(w io.Writer) (pf.Config, error) {
+	c := pf.Default()
+	set := make(map[string]bool)
+	o.fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	switch o.color {
+	case "auto":
+		c.ColorMode = c.ColorMode && isTerminal(w) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		c.ColorMode = true
+	case "never":
+		c.ColorMode = false
+	default:
+		return c, fmt.Errorf("invalid -color %q (want auto, always or never)", o.color)
+	}
+	switch o.format {
+	case "auto", formatJSON, formatNDJSON, formatYAML:
+	default:
+		return c, fmt.Errorf("invalid -format %q (want auto, json, ndjson or yaml)", o.format)
+	}
+	if o.indent < 0 {
+		return c, fmt.Errorf("invalid -indent %d", o.indent)
+	}
+	if set["indent"] {
+		c.Indent = fmt.Sprintf("%*s", o.indent, "")
+	}
+	if set["depth"] {
+		c.MaxDepth = o.depth
+	}
+	if set["width"] {
+		c.Width = o.width
+	}
+	if set["tree"] {
+		c.Tree = o.tree
+	}
+	if set["mask"] {
+		c.MaskSecrets = o.mask
+	}
+	return c, nil
+}
+
+// truncate shortens long strings and arrays in v according to the
+// -max-string and -max-items flags.
+func (o *outputOptions) truncate(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if r := []rune(v); o.maxString > 0 && len(r) > o.maxString {
+			return string(r[:o.maxString]) + "…"
+		}
+		return v
+	case []interface{}:
+		n := len(v)
+		if o.maxItems > 0 && n > o.maxItems {
+			n = o.maxItems
+		}
+		out := make([]interface{}, 0, n+1)
+		for _, e := range v[:n] {
+			out = append(out, o.truncate(e))
+		}
+		if n < len(v) {
+			out = append(out, fmt.Sprintf("… %d more", len(v)-n))
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = o.truncate(e)
+		}
+		return out
+	}
+	return v
+}
+
+// isTerminal reports whether w is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
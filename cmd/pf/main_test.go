@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nd-forge/pf"
+)
+
+func runPF(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestRun_JSON(t *testing.T) {
+	out, _, code := runPF(t, `{"name":"John","tags":["a","b"],"age":30}`, "-color", "never")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	want := `{
+  "age": 30,
+  "name": "John",
+  "tags": [
+    "a",
+    "b"
+  ]
+}
+`
+	if out != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestRun_NDJSON(t *testing.T) {
+	out, _, code := runPF(t, "{\"a\":1}\n{\"a\":2.5}\n", "-color", "never", "-indent", "4")
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if want := "{\n    \"a\": 1\n}\n{\n    \"a\": 2.5\n}\n"; out != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestRun_Options(t *testing.T) {
+	in := `{"s":"abcdefgh","list":[1,2,3,4],"deep":{"a":{"b":1}}}`
+	out, _, _ := runPF(t, in, "-color", "never", "-max-string", "3", "-max-items", "2", "-depth", "2")
+	for _, want := range []string{`"abc…"`, `"… 2 more"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `"b"`) {
+		t.Errorf("expected -depth to hide \"b\", got:\n%s", out)
+	}
+
+	out, _, _ = runPF(t, in, "-color", "always")
+	if !strings.Contains(out, "\033[") {
+		t.Errorf("expected colors with -color always, got:\n%s", out)
+	}
+
+	out, _, _ = runPF(t, `{"a":{"b":1},"c":2}`, "-color", "never", "-tree")
+	if want := "map[string]interface {}\n├── \"a\": map[string]interface {}\n│   └── \"b\": 1\n└── \"c\": 2\n"; out != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestRun_DefaultConfig(t *testing.T) {
+	prev := pf.Default()
+	defer pf.SetDefault(prev)
+	c := prev
+	c.Indent = "    "
+	c.MaxDepth = 1
+	pf.SetDefault(c) // as PF_INDENT=4 PF_DEPTH=1 would
+
+	in := `{"a":{"b":1},"c":2}`
+	out, _, _ := runPF(t, in, "-color", "never")
+	if want := "{\n    \"a\": map[string]interface {}(len=1),\n    \"c\": 2\n}\n"; out != want {
+		t.Errorf("expected the default config:\n%s\ngot:\n%s", want, out)
+	}
+	out, _, _ = runPF(t, in, "-color", "never", "-indent", "1", "-depth", "0")
+	if want := "{\n \"a\": {\n  \"b\": 1\n },\n \"c\": 2\n}\n"; out != want {
+		t.Errorf("expected flags to override it:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestRun_Files(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "a.yml")
+	jsonPath := filepath.Join(dir, "b.json")
+	os.WriteFile(yamlPath, []byte("name: a\n"), 0o644)
+	os.WriteFile(jsonPath, []byte(`{"name":"b"}`), 0o644)
+
+	out, _, code := runPF(t, "", "-color", "never", yamlPath, jsonPath)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+	if want := "{\n  \"name\": \"a\"\n}\n{\n  \"name\": \"b\"\n}\n"; out != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	cases := []struct {
+		stdin string
+		args  []string
+		code  int
+		msg   string
+	}{
+		{`[1,`, nil, 1, "<stdin>"},
+		{``, []string{"missing.json"}, 1, "missing.json"},
+		{``, []string{"-color", "sometimes"}, 2, "invalid -color"},
+		{``, []string{"-format", "xml"}, 2, "invalid -format"},
+		{``, []string{"-bogus"}, 2, "bogus"},
+	}
+	for _, tc := range cases {
+		_, stderr, code := runPF(t, tc.stdin, tc.args...)
+		if code != tc.code || !strings.Contains(stderr, tc.msg) {
+			t.Errorf("%v: expected exit code %d and %q, got %d and %q", tc.args, tc.code, tc.msg, code, stderr)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	cases := []struct {
+		name, data, want string
+	}{
+		{"a.json", "", formatJSON},
+		{"a.JSONL", "", formatNDJSON},
+		{"a.ndjson", "", formatNDJSON},
+		{"a.yaml", "{}", formatYAML},
+		{"<stdin>", "  [1]", formatJSON},
+		{"<stdin>", "a: 1", formatYAML},
+	}
+	for _, tc := range cases {
+		if got := detectFormat(tc.name, []byte(tc.data)); got != tc.want {
+			t.Errorf("detectFormat(%q, %q) = %q, want %q", tc.name, tc.data, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeJSON_Numbers(t *testing.T) {
+	docs, err := decodeJSON([]byte(`[1, -2, 1.5, 12345678901234567890, 1e999]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{int64(1), int64(-2), 1.5, 12345678901234567890.0, "1e999"}
+	if !reflect.DeepEqual(docs[0], want) {
+		t.Errorf("expected %v, got %v", want, docs[0])
+	}
+}
+
+func TestDecodeYAML(t *testing.T) {
+	src := `# config
+name: api   # trailing comment
+version: 3
+ratio: 0.5
+enabled: true
+empty:
+url: http://example.com/#top
+tags: [a, "b c", 'it''s']
+env:
+  - name: HOME
+    value: /root
+  - name: "PORT"
+    value: 8080
+ports:
+- 80
+- 0x1bb
+meta: {owner: team, "x": [1,
+  2]}
+script: |
+  echo hi
+  echo # not a comment
+
+folded: >-
+  one
+  two
+
+  three
+`
+	docs, err := decodeYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":    "api",
+		"version": int64(3),
+		"ratio":   0.5,
+		"enabled": true,
+		"empty":   nil,
+		"url":     "http://example.com/#top",
+		"tags":    []interface{}{"a", "b c", "it's"},
+		"env": []interface{}{
+			map[string]interface{}{"name": "HOME", "value": "/root"},
+			map[string]interface{}{"name": "PORT", "value": int64(8080)},
+		},
+		"ports":  []interface{}{int64(80), int64(443)},
+		"meta":   map[string]interface{}{"owner": "team", "x": []interface{}{int64(1), int64(2)}},
+		"script": "echo hi\necho # not a comment\n",
+		"folded": "one two\nthree",
+	}
+	if len(docs) != 1 || !reflect.DeepEqual(docs[0], want) {
+		t.Errorf("expected %#v, got %#v", want, docs)
+	}
+}
+
+func TestDecodeYAML_Documents(t *testing.T) {
+	docs, err := decodeYAML([]byte("---\na: 1\n---\n- x\n-\n  - y\n- - z\n...\n--- 42\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"a": int64(1)},
+		[]interface{}{"x", []interface{}{"y"}, []interface{}{"z"}},
+		int64(42),
+	}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("expected %#v, got %#v", want, docs)
+	}
+}
+
+func TestDecodeYAML_Scalars(t *testing.T) {
+	cases := []struct {
+		src  string
+		want interface{}
+	}{
+		{"~", nil},
+		{"False", false},
+		{"-12", int64(-12)},
+		{"1e3", 1000.0},
+		{"-.inf", math.Inf(-1)},
+		{"1_000", "1_000"},
+		{"0o17", int64(15)},
+		{`"tab\tnewline\n"`, "tab\tnewline\n"},
+		{"'#not a comment'", "#not a comment"},
+		{"v1.2.3", "v1.2.3"},
+		{"|+\n  keep\n\n", "keep\n\n"},
+		{"\t\n|\n  \ttab\n", "\ttab\n"},
+		{`"a\/b"`, "a/b"},
+		{`"\e[0m\_\N\L\P"`, "\x1b[0m\u00a0\u0085\u2028\u2029"},
+		{`"\x41\u00e9\U0001F600\0\ \	."`, "A\u00e9\U0001F600\x00 \t."},
+	}
+	for _, tc := range cases {
+		docs, err := decodeYAML([]byte(tc.src))
+		if err != nil {
+			t.Errorf("%q: %v", tc.src, err)
+			continue
+		}
+		if len(docs) != 1 || !reflect.DeepEqual(docs[0], tc.want) {
+			t.Errorf("%q: expected %#v, got %#v", tc.src, tc.want, docs)
+		}
+	}
+}
+
+func TestDecodeYAML_Errors(t *testing.T) {
+	cases := []struct {
+		src, msg string
+	}{
+		{"a: 1\n  b: 2\n", "line 2: unexpected indentation"},
+		{"a: &anchor 1\n", "anchors, aliases and tags"},
+		{"a: [1, 2\n", "unterminated flow collection"},
+		{"a: \"open\n", "unterminated quoted string"},
+		{"a: 1\njust text\n", "expected \"key: value\""},
+		{"? complex\n", "line 1"},
+		{"a:\n\tb: 1\n", "line 2: tabs are not allowed in indentation"},
+		{`a: "\q"`, `unknown escape \q`},
+		{`a: "\x4"`, `short escape \x4`},
+		{`a: "\UFFFFFFFF"`, `invalid escape \UFFFFFFFF`},
+		{`a: "\'"`, `unknown escape \'`},
+		{"- x\n\t- y\n", "line 2: tabs are not allowed in indentation"},
+	}
+	for _, tc := range cases {
+		_, err := decodeYAML([]byte(tc.src))
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%q: expected error containing %q, got %v", tc.src, tc.msg, err)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRunDiff(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"old.yaml":  "name: api\nversion: \"1\"\nreplicas: 2\n",
+		"new.json":  `{"name":"api","version":"2","replicas":2}`,
+		"same.json": `{"name":"api","version":"1","replicas":2}`,
+	})
+	old, new, same := filepath.Join(dir, "old.yaml"), filepath.Join(dir, "new.json"), filepath.Join(dir, "same.json")
+
+	out, _, code := runPF(t, "", "diff", "-color", "never", old, new)
+	if code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	for _, want := range []string{"--- " + old, "+++ " + new, `- version: "1"`, `+ version: "2"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	out, _, code = runPF(t, "", "diff", old, same)
+	if code != 0 || out != "" {
+		t.Errorf("expected exit code 0 and no output, got %d and %q", code, out)
+	}
+	out, _, code = runPF(t, "", "diff", "-ignore", "version", old, new)
+	if code != 0 || out != "" {
+		t.Errorf("expected ignored difference, got %d and %q", code, out)
+	}
+	out, _, code = runPF(t, "", "diff", "-q", old, new)
+	if code != 1 || out != "" {
+		t.Errorf("expected -q to print nothing, got %d and %q", code, out)
+	}
+	out, _, _ = runPF(t, "", "diff", "-color", "never", "-summary", old, new)
+	if !strings.Contains(out, "1 key modified\nchanged: [\"version\"]") {
+		t.Errorf("expected summary, got:\n%s", out)
+	}
+}
+
+func TestRunDiff_KeyAndContext(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.json": `{"items":[{"id":1,"v":"a"},{"id":2,"v":"b"}],"p":1,"q":2,"r":3,"s":4}`,
+		"b.json": `{"items":[{"id":2,"v":"b"},{"id":1,"v":"x"}],"p":1,"q":2,"r":3,"s":4}`,
+	})
+	a, b := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+
+	out, _, _ := runPF(t, "", "diff", "-color", "never", "-context", "0", a, b)
+	if !strings.Contains(out, "[0]: {") || !strings.Contains(out, "  s: 4\n") {
+		t.Errorf("expected index-matched full diff, got:\n%s", out)
+	}
+
+	out, _, _ = runPF(t, "", "diff", "-color", "never", "-key", "id", "-context", "1", a, b)
+	want := `--- ` + a + `
++++ ` + b + `
+...
+      id: 1
+      - v: "a"
+      + v: "x"
+    }
+    ...
+`
+	if out != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestRunDiff_UnchangedObjects(t *testing.T) {
+	// Unchanged objects are written in the same syntax as changed ones.
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "items:\n  - name: x\n    v: 1\n  - name: y\n    v: 2\n",
+		"b.yaml": "items:\n  - name: x\n    v: 3\n  - name: y\n    v: 2\n",
+	})
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+	out, _, _ := runPF(t, "", "diff", "-color", "never", "-key", "name", "-context", "0", a, b)
+	want := `--- ` + a + `
++++ ` + b + `
+{
+  items: {
+    name=x: {
+      name: "x"
+      - v: 1
+      + v: 3
+    }
+    name=y: {
+      name: "y"
+      v: 2
+    }
+  }
+}
+`
+	if out != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func TestRunDiff_EmptyFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.json": `{"a":1}`, "empty.json": ""})
+	a, empty := filepath.Join(dir, "a.json"), filepath.Join(dir, "empty.json")
+	out, _, code := runPF(t, "", "diff", "-color", "never", a, empty)
+	want := "--- " + a + "\n+++ " + empty + "\n- {\n    \"a\": 1\n  }\n+ nil\n"
+	if code != 1 || out != want {
+		t.Errorf("expected exit code 1 and:\n%s\ngot %d and:\n%s", want, code, out)
+	}
+	if _, _, code := runPF(t, "", "diff", empty, empty); code != 0 {
+		t.Errorf("expected empty files to be equal, got exit code %d", code)
+	}
+}
+
+func TestRunDiff_Errors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.json": `{}`, "bad.json": `{`})
+	a := filepath.Join(dir, "a.json")
+	cases := []struct {
+		args []string
+		msg  string
+	}{
+		{[]string{a}, "usage: pf diff"},
+		{[]string{a, filepath.Join(dir, "bad.json")}, "bad.json"},
+		{[]string{"-ignore", "a[x]", a, a}, "invalid index"},
+		{[]string{"-context", "-1", a, a}, "invalid -context"},
+	}
+	for _, tc := range cases {
+		_, stderr, code := runPF(t, "", append([]string{"diff"}, tc.args...)...)
+		if code != 2 || !strings.Contains(stderr, tc.msg) {
+			t.Errorf("%v: expected exit code 2 and %q, got %d and %q", tc.args, tc.msg, code, stderr)
+		}
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []pathStep
+	}{
+		{"a.b", []pathStep{{key: "a"}, {key: "b"}}},
+		{".items[*].id", []pathStep{{key: "items"}, {key: "*", wildcard: true}, {key: "id"}}},
+		{`meta["a.b"][2]`, []pathStep{{key: "meta"}, {key: "a.b"}, {key: "2"}}},
+		{"/a~1b/0", []pathStep{{key: "a/b"}, {key: "0"}}},
+	}
+	for _, tc := range cases {
+		got, err := parsePath(tc.in)
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("parsePath(%q) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"", "a.", "a[", "a..b"} {
+		if _, err := parsePath(bad); err == nil {
+			t.Errorf("parsePath(%q): expected error", bad)
+		}
+	}
+}
+
+func TestRemovePath(t *testing.T) {
+	docs, _ := decodeJSON([]byte(`{"items":[{"id":1,"n":"a"},{"id":2,"n":"b"}],"meta":{"uid":"x","name":"m"}}`))
+	v := docs[0]
+	for _, p := range []string{"items[*].id", "meta.uid", "items[1]"} {
+		steps, _ := parsePath(p)
+		v = removePath(v, steps)
+	}
+	want := map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{"n": "a"}},
+		"meta":  map[string]interface{}{"name": "m"},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("expected %v, got %v", want, v)
+	}
+}
+
+func TestKeyArrays(t *testing.T) {
+	docs, _ := decodeJSON([]byte(`{"a":[{"name":"x"},{"name":"y"}],"dup":[{"name":"x"},{"name":"x"}],"mixed":[{"name":"x"},1]}`))
+	got := keyArrays(docs[0], []string{"id", "name"}).(map[string]interface{})
+	if _, ok := got["a"].(map[string]interface{})["name=y"]; !ok {
+		t.Errorf("expected keyed array, got %v", got["a"])
+	}
+	if _, ok := got["dup"].([]interface{}); !ok {
+		t.Errorf("expected duplicate keys to stay an array, got %v", got["dup"])
+	}
+	if _, ok := got["mixed"].([]interface{}); !ok {
+		t.Errorf("expected mixed array to stay an array, got %v", got["mixed"])
+	}
+}
@@ -0,0 +1,639 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeYAML decodes the YAML documents in data into generic values.
+//
+// It supports the subset of YAML found in configuration files and API
+// fixtures: block mappings and sequences, flow collections on one or
+// more lines, plain, quoted, literal (|) and folded (>) scalars,
+// comments, and "---" document separators. Anchors, aliases, tags and
+// complex keys are reported as errors rather than misread.
+func decodeYAML(data []byte) ([]interface{}, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.TrimPrefix(text, "\uFEFF")
+	text = strings.TrimSuffix(text, "\n")
+	p := &yamlParser{lines: strings.Split(text, "\n")}
+
+	var docs []interface{}
+	for p.pos < len(p.lines) {
+		if _, _, ok := p.peek(); ok {
+			v, err := p.parseNode(0)
+			if err == nil {
+				err = p.err
+			}
+			if err != nil {
+				return nil, err
+			}
+			if _, _, ok := p.peek(); ok {
+				return nil, p.errorf("unexpected content after document")
+			}
+			docs = append(docs, v)
+			continue
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		if p.pos >= len(p.lines) {
+			break
+		}
+		// At a "---" or "..." document marker. Content after "---"
+		// starts the next document on the same line.
+		line := p.lines[p.pos]
+		if rest := strings.TrimSpace(stripComment(strings.TrimPrefix(line, "---"))); line != "..." && rest != "" {
+			p.lines[p.pos] = rest
+		} else {
+			p.pos++
+		}
+	}
+	return docs, nil
+}
+
+type yamlParser struct {
+	lines []string
+	pos   int
+	// err is set by peek on a line it cannot read, which then ends the
+	// document as if it were the end of the input.
+	err error
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// peek skips blank and comment lines and returns the indentation and
+// content of the next line without consuming it. ok is false at the
+// end of the input, at a document marker, or at a line indented with
+// tabs, which YAML forbids; the last sets p.err.
+func (p *yamlParser) peek() (indent int, text string, ok bool) {
+	if p.err != nil {
+		return 0, "", false
+	}
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if isDocumentMarker(line) {
+			return 0, "", false
+		}
+		text := strings.TrimSpace(stripComment(line))
+		if text == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if line[indent] == '\t' {
+			p.err = p.errorf("tabs are not allowed in indentation")
+			return 0, "", false
+		}
+		return indent, text, true
+	}
+	return 0, "", false
+}
+
+// parseNode parses the value starting at the next line, which must be
+// indented at least minIndent; otherwise the value is null.
+func (p *yamlParser) parseNode(minIndent int) (interface{}, error) {
+	indent, text, ok := p.peek()
+	if !ok || indent < minIndent {
+		return nil, nil
+	}
+	switch {
+	case text == "?" || strings.HasPrefix(text, "? "):
+		return nil, p.errorf("complex keys are not supported")
+	case isSeqItem(text):
+		return p.parseSeq(indent)
+	case mappingColon(text) >= 0:
+		return p.parseMap(indent)
+	case isBlockIndicator(text):
+		p.pos++
+		return p.parseBlockScalar(text, minIndent-1)
+	}
+	p.pos++
+	return p.parseInline(text)
+}
+
+func (p *yamlParser) parseSeq(indent int) (interface{}, error) {
+	seq := []interface{}{}
+	for {
+		ind, text, ok := p.peek()
+		// A sequence that is a mapping value may sit at the key's
+		// indentation, so a non-item line there ends it.
+		if !ok || ind < indent || ind == indent && !isSeqItem(text) {
+			return seq, nil
+		}
+		if ind > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		rest := strings.TrimLeft(text[1:], " ")
+		var item interface{}
+		var err error
+		switch {
+		case rest == "":
+			p.pos++
+			item, err = p.parseNode(indent + 1)
+		case isBlockIndicator(rest):
+			p.pos++
+			item, err = p.parseBlockScalar(rest, indent)
+		default:
+			// Parse the rest of the line as if it started on a line of
+			// its own at the same column, so "- key: v" opens a mapping
+			// whose later keys line up under "key".
+			col := ind + len(text) - len(rest)
+			p.lines[p.pos] = strings.Repeat(" ", col) + rest
+			item, err = p.parseNode(col)
+		}
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, item)
+	}
+}
+
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for {
+		ind, text, ok := p.peek()
+		if !ok || ind < indent {
+			return m, nil
+		}
+		if ind > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		i := mappingColon(text)
+		if i < 0 {
+			return nil, p.errorf("expected \"key: value\", found %q", text)
+		}
+		key, err := p.parseKey(text[:i])
+		if err != nil {
+			return nil, err
+		}
+		rest := strings.TrimSpace(text[i+1:])
+		p.pos++
+
+		var v interface{}
+		switch {
+		case rest == "":
+			// The value is on the following lines: indented further, or
+			// a sequence at the same indentation as the key.
+			ni, nt, ok := p.peek()
+			switch {
+			case ok && ni > indent:
+				v, err = p.parseNode(ni)
+			case ok && ni == indent && isSeqItem(nt):
+				v, err = p.parseSeq(indent)
+			}
+		case isBlockIndicator(rest):
+			v, err = p.parseBlockScalar(rest, indent)
+		default:
+			v, err = p.parseInline(rest)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+}
+
+func (p *yamlParser) parseKey(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return "", p.errorf("empty mapping key")
+	case s[0] == '"' || s[0] == '\'':
+		n, key, err := scanQuoted(s)
+		if err != nil {
+			return "", p.errorf("%v", err)
+		}
+		if n != len(s) {
+			return "", p.errorf("unexpected text after quoted key %q", s)
+		}
+		return key, nil
+	case strings.ContainsRune("?&*!", rune(s[0])):
+		return "", p.errorf("unsupported key %q", s)
+	}
+	return s, nil
+}
+
+// parseInline parses a scalar or flow collection that starts on the
+// current line. Flow collections may continue on the following lines.
+func (p *yamlParser) parseInline(text string) (interface{}, error) {
+	if text[0] == '[' || text[0] == '{' {
+		for flowDepth(text) > 0 && p.pos < len(p.lines) && !isDocumentMarker(p.lines[p.pos]) {
+			text += " " + strings.TrimSpace(stripComment(p.lines[p.pos]))
+			p.pos++
+		}
+	}
+	if strings.ContainsRune("&*!", rune(text[0])) {
+		return nil, p.errorf("anchors, aliases and tags are not supported: %q", text)
+	}
+	f := &flowParser{s: text}
+	v, err := f.value(false)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	f.skipSpace()
+	if f.i != len(f.s) {
+		return nil, p.errorf("unexpected text %q", f.s[f.i:])
+	}
+	return v, nil
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) scalar whose
+// content lines are indented more than parentIndent.
+func (p *yamlParser) parseBlockScalar(header string, parentIndent int) (interface{}, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	contentIndent := -1
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			contentIndent = parentIndent + 1 + int(c-'1')
+		default:
+			return nil, p.errorf("invalid block scalar header %q", header)
+		}
+	}
+
+	var body []string
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if isDocumentMarker(line) {
+			break
+		}
+		if strings.TrimSpace(line) == "" {
+			body = append(body, "")
+			continue
+		}
+		ind := len(line) - len(strings.TrimLeft(line, " "))
+		if contentIndent < 0 {
+			contentIndent = ind
+		}
+		if ind < contentIndent || ind <= parentIndent {
+			break
+		}
+		body = append(body, line[contentIndent:])
+	}
+
+	trailing := 0
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+		trailing++
+	}
+
+	var sb strings.Builder
+	for i, line := range body {
+		switch {
+		case i == 0:
+		case !folded:
+			sb.WriteByte('\n')
+		case line == "":
+			sb.WriteByte('\n')
+			continue
+		case body[i-1] == "":
+			// The blank line already produced the line break.
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(body[i-1], " "):
+			sb.WriteByte('\n')
+		default:
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(line)
+	}
+
+	switch {
+	case chomp == '-' || len(body) == 0 && chomp != '+':
+	case chomp == '+':
+		sb.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// flowParser parses flow-style values: [a, b], {k: v}, quoted and plain
+// scalars.
+type flowParser struct {
+	s string
+	i int
+}
+
+func (f *flowParser) skipSpace() {
+	for f.i < len(f.s) && (f.s[f.i] == ' ' || f.s[f.i] == '\t') {
+		f.i++
+	}
+}
+
+// value parses the value at the current position. Inside a flow
+// collection, plain scalars end at a flow indicator.
+func (f *flowParser) value(inFlow bool) (interface{}, error) {
+	f.skipSpace()
+	if f.i == len(f.s) {
+		return nil, nil
+	}
+	switch f.s[f.i] {
+	case '[':
+		return f.seq()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		n, s, err := scanQuoted(f.s[f.i:])
+		f.i += n
+		return s, err
+	}
+	return resolvePlain(f.plain(inFlow, false)), nil
+}
+
+// plain scans a plain scalar. Inside flow collections it stops at ",",
+// "]" and "}"; map keys also stop at ": ".
+func (f *flowParser) plain(inFlow, key bool) string {
+	start := f.i
+	for ; f.i < len(f.s); f.i++ {
+		c := f.s[f.i]
+		if inFlow && (c == ',' || c == ']' || c == '}') {
+			break
+		}
+		if key && c == ':' && (f.i+1 == len(f.s) || strings.IndexByte(" \t,]}", f.s[f.i+1]) >= 0) {
+			break
+		}
+	}
+	return strings.TrimSpace(f.s[start:f.i])
+}
+
+func (f *flowParser) seq() (interface{}, error) {
+	f.i++ // [
+	seq := []interface{}{}
+	for {
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ']' {
+			f.i++
+			return seq, nil
+		}
+		v, err := f.value(true)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, v)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *flowParser) mapping() (interface{}, error) {
+	f.i++ // {
+	m := map[string]interface{}{}
+	for {
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == '}' {
+			f.i++
+			return m, nil
+		}
+		var key string
+		if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+			n, s, err := scanQuoted(f.s[f.i:])
+			if err != nil {
+				return nil, err
+			}
+			f.i += n
+			key = s
+		} else {
+			key = f.plain(true, true)
+		}
+		f.skipSpace()
+		var v interface{}
+		if f.i < len(f.s) && f.s[f.i] == ':' {
+			f.i++
+			var err error
+			if v, err = f.value(true); err != nil {
+				return nil, err
+			}
+		}
+		m[key] = v
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the "," between flow entries. The closing bracket
+// is left for the caller.
+func (f *flowParser) separator(closing byte) error {
+	f.skipSpace()
+	switch {
+	case f.i == len(f.s):
+		return fmt.Errorf("unterminated flow collection, expected %q", closing)
+	case f.s[f.i] == ',':
+		f.i++
+		return nil
+	case f.s[f.i] == closing:
+		return nil
+	}
+	return fmt.Errorf("expected \",\" or %q, found %q", closing, f.s[f.i:])
+}
+
+// scanQuoted parses the quoted string at the start of s and returns the
+// number of bytes it spans and its value.
+func scanQuoted(s string) (int, string, error) {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			if q == '\'' {
+				return i + 1, strings.ReplaceAll(s[1:i], "''", "'"), nil
+			}
+			v, err := unescapeDouble(s[1:i])
+			if err != nil {
+				return 0, "", fmt.Errorf("invalid double-quoted string %s: %v", s[:i+1], err)
+			}
+			return i + 1, v, nil
+		}
+	}
+	return 0, "", fmt.Errorf("unterminated quoted string %s", s)
+}
+
+// yamlEscapes are the single-character escapes of double-quoted YAML
+// strings.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028",
+	'P': "\u2029",
+}
+
+// unescapeDouble decodes the escape sequences in s, the text between
+// the quotes of a double-quoted YAML string.
+func unescapeDouble(s string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i++; i == len(s) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+		if e, ok := yamlEscapes[s[i]]; ok {
+			sb.WriteString(e)
+			continue
+		}
+		n := map[byte]int{'x': 2, 'u': 4, 'U': 8}[s[i]]
+		if n == 0 {
+			return "", fmt.Errorf("unknown escape \\%c", s[i])
+		}
+		if i+n >= len(s) {
+			return "", fmt.Errorf("short escape \\%s", s[i:])
+		}
+		r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return "", fmt.Errorf("invalid escape \\%s", s[i:i+1+n])
+		}
+		sb.WriteRune(rune(r))
+		i += n
+	}
+	return sb.String(), nil
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+// resolvePlain converts a plain scalar to null, a bool, a number or a
+// string following the YAML 1.2 core schema.
+func resolvePlain(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	switch {
+	case yamlInt.MatchString(s):
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0o"):
+		if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return i
+		}
+		return s
+	}
+	if yamlFloat.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// stripComment removes a trailing "# comment" outside of quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			// Quotes only open a string at the start of a token.
+			if i == 0 || strings.IndexByte(" \t[{,:-", line[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// mappingColon returns the index of the ":" separating a block mapping
+// key from its value, or -1 if text is not a mapping entry.
+func mappingColon(text string) int {
+	if text[0] == '[' || text[0] == '{' {
+		return -1
+	}
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		n, _, err := scanQuoted(text)
+		if err != nil {
+			return -1
+		}
+		start = n
+	}
+	for i := start; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+			return i
+		}
+		if start > 0 && text[i] != ' ' {
+			// Only whitespace may follow a quoted key before the colon.
+			return -1
+		}
+	}
+	return -1
+}
+
+// flowDepth returns how many flow collections are left open in s.
+func flowDepth(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func isBlockIndicator(text string) bool {
+	if text[0] != '|' && text[0] != '>' {
+		return false
+	}
+	for _, c := range text[1:] {
+		if c != '-' && c != '+' && (c < '1' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isDocumentMarker(line string) bool {
+	return line == "---" || line == "..." || strings.HasPrefix(line, "--- ")
+}
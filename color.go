@@ -14,25 +14,45 @@ const (
 	cBrace   = "\033[37m" // white - braces/brackets
 	cDiffDel = "\033[31m" // red - diff deletions
 	cDiffAdd = "\033[32m" // green - diff additions
+
+	cDiffDelHi = "\033[7;31m" // inverse red - changed text within a deletion
+	cDiffAddHi = "\033[7;32m" // inverse green - changed text within an addition
 )
 
 func (f *formatter) colored(color, text string) {
-	if f.config.ColorMode {
-		f.sb.WriteString(color)
-		f.sb.WriteString(text)
-		f.sb.WriteString(cReset)
-	} else {
-		f.sb.WriteString(text)
+	f.sb.WriteString(f.config.paint(color, text, false))
+}
+
+// paint wraps text in color, one of the constants above, if ColorMode
+// is set. diff tells that red and green mark deletions and additions
+// rather than nil values and strings, for Config.Theme.
+func (c Config) paint(color, text string, diff bool) string {
+	if !c.ColorMode {
+		return text
 	}
+	var sb strings.Builder
+	sb.WriteString(c.Theme.sgr(color, diff))
+	sb.WriteString(text)
+	sb.WriteString(cReset)
+	return sb.String()
 }
 
-func coloredStr(color, text string, colorMode bool) string {
-	if colorMode {
-		var sb strings.Builder
-		sb.WriteString(color)
-		sb.WriteString(text)
-		sb.WriteString(cReset)
-		return sb.String()
+// stripANSI removes ANSI color sequences from s.
+func stripANSI(s string) string {
+	if !strings.Contains(s, "\033[") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\033' && i+1 < len(s) && s[i+1] == '[' {
+			j := i + 2
+			for j < len(s) && s[j] != 'm' {
+				j++
+			}
+			i = j
+			continue
+		}
+		sb.WriteByte(s[i])
 	}
-	return text
+	return sb.String()
 }
@@ -14,17 +14,63 @@ type Config struct {
 	Indent string
 	// ShowTypes annotates values with their type name.
 	ShowTypes bool
-	// UseJSONTags uses json tag names instead of Go field names.
+	// UseJSONTags uses json tag names instead of Go field names, and
+	// prints the fields encoding/json would encode, with the fields of
+	// embedded structs promoted.
 	UseJSONTags bool
+	// Embedded is how the fields of embedded structs are printed when
+	// not using json tags: nested (the default), flattened or marked.
+	Embedded EmbedMode
 	// MaxDepth limits nesting depth (0 = unlimited).
 	MaxDepth int
 	// ColorMode enables ANSI color output.
 	ColorMode bool
+	// Theme overrides the colors used with ColorMode.
+	Theme Theme
+	// Width is the maximum line width. When set, structs, maps and slices
+	// that fit on the rest of the line are printed on one line. The
+	// side-by-side diff sizes its columns to it (0 = terminal width, or
+	// 120 if unknown).
+	Width int
+	// GoSyntax renders values as Go composite literals, like %#v.
+	GoSyntax bool
+	// DiffSummary prefixes diffs with a one-line count of the changes
+	// and the list of changed paths.
+	DiffSummary bool
+	// DiffContext, when set, limits diffs to this many unchanged lines
+	// around each change; longer runs are shown as "..." (0 = all lines).
+	DiffContext int
+	// OldLabel and NewLabel, when set, prefix diffs with a
+	// "--- OldLabel" / "+++ NewLabel" header.
+	OldLabel string
+	NewLabel string
+	// TableSlices prints slices and maps of structs or maps as tables,
+	// like SprintTable.
+	TableSlices bool
+	// Tree draws nested values as a tree, with each entry on a branch
+	// under its parent, instead of in braces. Diffs use it too.
+	Tree bool
+	// ASCII draws tables and trees with plain ASCII characters instead
+	// of box-drawing ones.
+	ASCII bool
+	// Rules expand or collapse values by path or type when printing.
+	// When several rules match a value, the last one wins. Diffs and
+	// Equal ignore rules, so a collapsed value cannot hide a change.
+	Rules []Rule
+	// MaskSecrets prints values that look like secrets as <masked>:
+	// strings and byte slices in fields or map keys named like password,
+	// secret, token or authorization, and strings holding a JWT, an AWS
+	// access key ID, a PEM block or a card number. Diffs still report
+	// that a masked value changed.
+	MaskSecrets bool
+
+	// html marks where blocks start and end, for the HTML renderer.
+	html bool
 }
 
 // Sprint returns a pretty-printed string using this config.
 func (c Config) Sprint(v interface{}) string {
-	f := &formatter{config: c}
+	f := c.newFormatter()
 	f.format(reflect.ValueOf(v), 0)
 	return f.sb.String()
 }
@@ -45,7 +91,33 @@ func (c Config) SprintDiff(a, b interface{}) string {
 	return d.diff(a, b)
 }
 
+// SprintSideBySide returns a two-column diff using this config.
+func (c Config) SprintSideBySide(a, b interface{}) string {
+	s := &sideBySide{config: c}
+	return s.render(a, b)
+}
+
 type formatter struct {
 	config Config
 	sb     strings.Builder
+	// singleLine renders composite values on one line.
+	singleLine bool
+	rules      []compiledRule
+	// path is the location of the value being formatted, tracked only
+	// when there are rules or MaskSecrets is set.
+	path []child
+	// name is the name given to the formatted value as a whole, for
+	// MaskSecrets.
+	name string
+	// expanded counts the enclosing values matched by an Expand rule.
+	expanded int
+	// inKey is set while formatting a map key, which rules don't apply to.
+	inKey bool
+	// branches holds the tree continuation drawn for each enclosing
+	// entry, e.g. "│   ".
+	branches []string
+}
+
+func (c Config) newFormatter() *formatter {
+	return &formatter{config: c, rules: compileRules(c.Rules)}
 }
@@ -3,18 +3,35 @@ package pf
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 )
 
+// diffContextLines is the number of unchanged lines shown around each
+// change in a multi-line string diff.
+const diffContextLines = 3
+
 type differ struct {
 	config Config
 	sb     strings.Builder
+	// changed holds the [start, end) byte ranges of changed lines in sb.
+	changed [][2]int
+	// prefix is the tree continuation before the entries of the current
+	// block, and cont the one below the current entry (Config.Tree).
+	// Without Tree, cont is the indentation of the current entry.
+	prefix, cont string
+	// name is the field or key name of the entry being diffed, for
+	// MaskSecrets.
+	name string
 }
 
 // diff compares two values and returns a formatted diff string.
 // For structs, it shows changed fields with -/+ markers.
 // For non-structs, it shows a simple before/after.
 func (d *differ) diff(a, b interface{}) string {
+	d.writeHeader(a, b)
+	header := d.sb.Len()
+
 	va := reflect.ValueOf(a)
 	vb := reflect.ValueOf(b)
 
@@ -26,82 +43,214 @@ func (d *differ) diff(a, b interface{}) string {
 		vb = vb.Elem()
 	}
 
-	if va.Type() != vb.Type() {
-		d.writeLine("", fmt.Sprintf("type mismatch: %s vs %s", va.Type(), vb.Type()))
-		return d.sb.String()
+	// An untyped nil is printed as nil against the other value, which
+	// keeps its pointer so that methods such as Error are used, unless
+	// that prints as nil too.
+	oa, ob := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !sameType(oa, ob) {
+		mismatch := va.IsValid() && vb.IsValid()
+		if !mismatch {
+			_, _, mismatch = d.sprintPair(oa, ob)
+		}
+		if mismatch {
+			d.markChanged(func() {
+				d.writeLine("", fmt.Sprintf("type mismatch: %s vs %s", typeName(va), typeName(vb)))
+			})
+			return d.sb.String()
+		}
 	}
 
-	switch va.Kind() {
-	case reflect.Struct:
+	switch {
+	case !va.IsValid() || !vb.IsValid():
+		d.diffScalar(oa, ob, 0)
+	case va.Kind() == reflect.Struct:
 		d.diffStruct(va, vb, 0)
-	case reflect.Map:
+	case va.Kind() == reflect.Map:
 		d.diffMap(va, vb, 0)
-	case reflect.Slice, reflect.Array:
+	case va.Kind() == reflect.Slice || va.Kind() == reflect.Array:
 		d.diffSlice(va, vb, 0)
 	default:
 		d.diffScalar(va, vb, 0)
 	}
 
-	return d.sb.String()
+	if d.config.DiffContext > 0 {
+		return d.elideUnchanged(header, d.config.DiffContext)
+	}
+	// Trees have no closing brace; drop the final newline.
+	return strings.TrimSuffix(d.sb.String(), "\n")
 }
 
-func (d *differ) diffStruct(a, b reflect.Value, depth int) {
-	t := a.Type()
-	indent := strings.Repeat(d.config.Indent, depth+1)
-	closingIndent := strings.Repeat(d.config.Indent, depth)
-	cm := d.config.ColorMode
+// markChanged records the output written by write as changed lines.
+func (d *differ) markChanged(write func()) {
+	start := d.sb.Len()
+	write()
+	d.changed = append(d.changed, [2]int{start, d.sb.Len()})
+}
 
-	typeName := t.Name()
-	if d.config.ShowTypes && typeName != "" {
-		d.sb.WriteString(coloredStr(cType, typeName+" ", cm))
+// elideUnchanged returns the output with runs of unchanged lines more
+// than context lines away from a change replaced by a "..." line. The
+// header before offset from is kept as is.
+func (d *differ) elideUnchanged(from, context int) string {
+	out := d.sb.String()
+	if len(d.changed) == 0 {
+		return out
+	}
+	var lines []string
+	for _, line := range strings.SplitAfter(out[from:], "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
 	}
-	d.sb.WriteString(coloredStr(cBrace, "{\n", cm))
 
-	for i := 0; i < a.NumField(); i++ {
-		sf := t.Field(i)
-		if !sf.IsExported() {
-			continue
+	keep := make([]bool, len(lines))
+	pos, r := from, 0
+	for i, line := range lines {
+		end := pos + len(line)
+		for r < len(d.changed) && d.changed[r][1] <= pos {
+			r++
 		}
+		if r < len(d.changed) && d.changed[r][0] < end {
+			for j := i - context; j <= i+context; j++ {
+				if j >= 0 && j < len(lines) {
+					keep[j] = true
+				}
+			}
+		}
+		pos = end
+	}
 
-		name := d.fieldName(sf)
-		if name == "" {
+	var sb strings.Builder
+	sb.WriteString(out[:from])
+	for i := 0; i < len(lines); i++ {
+		// Eliding a single line would not shorten the output.
+		if keep[i] || (i == 0 || keep[i-1]) && (i+1 == len(lines) || keep[i+1]) {
+			sb.WriteString(lines[i])
 			continue
 		}
+		indent := lines[i][:len(lines[i])-len(strings.TrimLeft(lines[i], " │|"))]
+		sb.WriteString(indent + d.paint(cType, "...") + "\n")
+		for i+1 < len(lines) && !keep[i+1] {
+			i++
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
 
-		fa := a.Field(i)
-		fb := b.Field(i)
+// writeHeader writes the optional label header and summary lines.
+func (d *differ) writeHeader(a, b interface{}) {
+	if d.config.OldLabel != "" || d.config.NewLabel != "" {
+		d.sb.WriteString(d.paint(cDiffDel, "--- "+d.config.OldLabel))
+		d.sb.WriteString("\n")
+		d.sb.WriteString(d.paint(cDiffAdd, "+++ "+d.config.NewLabel))
+		d.sb.WriteString("\n")
+	}
+	if d.config.DiffSummary {
+		s := d.config.Summarize(a, b)
+		d.sb.WriteString(d.paint(cType, s.String()))
+		d.sb.WriteString("\n")
+		var paths []string
+		for _, p := range s.Paths() {
+			if p != "" { // a changed top-level value has an empty path
+				paths = append(paths, p)
+			}
+		}
+		if len(paths) > 0 {
+			d.sb.WriteString(d.paint(cType, "changed: "+strings.Join(paths, ", ")))
+			d.sb.WriteString("\n")
+		}
+	}
+}
 
-		aStr := d.sprintValue(fa)
-		bStr := d.sprintValue(fb)
+func (d *differ) diffStruct(a, b reflect.Value, depth int) {
+	t := a.Type()
 
-		if aStr == bStr {
-			// Unchanged
-			d.sb.WriteString(indent)
-			d.sb.WriteString(coloredStr(cKey, name, cm))
-			d.sb.WriteString(": ")
-			d.sb.WriteString(aStr)
-			d.sb.WriteString("\n")
-		} else {
-			// Changed
-			d.sb.WriteString(indent)
-			d.sb.WriteString(coloredStr(cDiffDel, "- "+name+": "+aStr, cm))
-			d.sb.WriteString("\n")
-			d.sb.WriteString(indent)
-			d.sb.WriteString(coloredStr(cDiffAdd, "+ "+name+": "+bStr, cm))
-			d.sb.WriteString("\n")
+	typeName := t.Name()
+	if d.config.ShowTypes && typeName != "" && !d.config.Tree {
+		d.sb.WriteString(d.paint(cType, typeName+" "))
+	}
+	d.openBlock("{", shortType(t))
+	defer func(name string) { d.name = name }(d.name)
+
+	// Fields promoted through a nil embedded pointer or left out by
+	// omitempty or omitzero are missing.
+	type entry struct {
+		name   string
+		av, bv reflect.Value
+		aok    bool
+		bok    bool
+	}
+	var entries []entry
+	for _, sf := range d.config.structFields(t) {
+		av, aok := sf.value(a)
+		bv, bok := sf.value(b)
+		aok = aok && !sf.omitted(av)
+		bok = bok && !sf.omitted(bv)
+		if aok || bok {
+			entries = append(entries, entry{sf.name, av, bv, aok, bok})
+		}
+	}
+	for n, e := range entries {
+		indent := d.entryIndent(depth, n, len(entries))
+		d.name = e.name
+		switch {
+		case !e.bok:
+			d.writeDel(indent, e.name+": "+d.sprintValue(e.av))
+		case !e.aok:
+			d.writeAdd(indent, e.name+": "+d.sprintValue(e.bv))
+		default:
+			d.diffEntry(indent, d.paint(cKey, e.name)+": ", e.name+": ", e.av, e.bv, depth)
 		}
 	}
 
-	d.sb.WriteString(closingIndent)
-	d.sb.WriteString(coloredStr(cBrace, "}", cm))
+	d.closeBlock("}", depth)
 }
 
-func (d *differ) diffMap(a, b reflect.Value, depth int) {
-	indent := strings.Repeat(d.config.Indent, depth+1)
-	closingIndent := strings.Repeat(d.config.Indent, depth)
-	cm := d.config.ColorMode
+// paint colors text for a diff.
+func (d *differ) paint(color, text string) string {
+	return d.config.paint(color, text, true)
+}
+
+// openBlock writes the opening brace of a struct, map or slice, or in a
+// tree, its type.
+func (d *differ) openBlock(brace, typ string) {
+	if d.config.Tree {
+		d.sb.WriteString(d.paint(cType, typ) + "\n")
+		return
+	}
+	if d.config.html {
+		d.sb.WriteString(blockStart)
+	}
+	d.sb.WriteString(d.paint(cBrace, brace+"\n"))
+}
 
-	d.sb.WriteString(coloredStr(cBrace, "{\n", cm))
+// closeBlock writes the closing brace at depth. Trees have none.
+func (d *differ) closeBlock(brace string, depth int) {
+	if d.config.Tree {
+		return
+	}
+	d.sb.WriteString(strings.Repeat(d.config.Indent, depth))
+	if d.config.html {
+		d.sb.WriteString(blockEnd)
+	}
+	d.sb.WriteString(d.paint(cBrace, brace))
+}
+
+// entryIndent returns what is written before entry i of n at depth+1:
+// its indentation, or in a tree, the branches leading to it. It sets
+// d.cont for the entry's following lines.
+func (d *differ) entryIndent(depth, i, n int) string {
+	if !d.config.Tree {
+		d.cont = strings.Repeat(d.config.Indent, depth+1)
+		return d.cont
+	}
+	branch, cont := treeBranch(i == n-1, d.config.ASCII)
+	d.cont = d.prefix + cont
+	return d.prefix + branch
+}
+
+func (d *differ) diffMap(a, b reflect.Value, depth int) {
+	d.openBlock("{", shortType(a.Type()))
+	defer func(name string) { d.name = name }(d.name)
 
 	// Collect all keys from both maps
 	allKeys := make(map[string]reflect.Value)
@@ -112,50 +261,32 @@ func (d *differ) diffMap(a, b reflect.Value, depth int) {
 		allKeys[fmt.Sprint(k.Interface())] = k
 	}
 
-	for _, key := range sortMapKeys(collectValues(allKeys)) {
+	keys := sortMapKeys(collectValues(allKeys))
+	for i, key := range keys {
+		indent := d.entryIndent(depth, i, len(keys))
 		keyStr := fmt.Sprint(key.Interface())
 		aVal := a.MapIndex(key)
 		bVal := b.MapIndex(key)
 
 		aExists := aVal.IsValid()
 		bExists := bVal.IsValid()
+		d.name = keyStr
 
 		switch {
 		case aExists && !bExists:
-			d.sb.WriteString(indent)
-			d.sb.WriteString(coloredStr(cDiffDel, "- "+keyStr+": "+d.sprintValue(aVal), cm))
-			d.sb.WriteString("\n")
+			d.writeDel(indent, keyStr+": "+d.sprintValue(aVal))
 		case !aExists && bExists:
-			d.sb.WriteString(indent)
-			d.sb.WriteString(coloredStr(cDiffAdd, "+ "+keyStr+": "+d.sprintValue(bVal), cm))
-			d.sb.WriteString("\n")
+			d.writeAdd(indent, keyStr+": "+d.sprintValue(bVal))
 		default:
-			aStr := d.sprintValue(aVal)
-			bStr := d.sprintValue(bVal)
-			if aStr == bStr {
-				d.sb.WriteString(indent)
-				d.sb.WriteString(keyStr + ": " + aStr + "\n")
-			} else {
-				d.sb.WriteString(indent)
-				d.sb.WriteString(coloredStr(cDiffDel, "- "+keyStr+": "+aStr, cm))
-				d.sb.WriteString("\n")
-				d.sb.WriteString(indent)
-				d.sb.WriteString(coloredStr(cDiffAdd, "+ "+keyStr+": "+bStr, cm))
-				d.sb.WriteString("\n")
-			}
+			d.diffEntry(indent, keyStr+": ", keyStr+": ", aVal, bVal, depth)
 		}
 	}
 
-	d.sb.WriteString(closingIndent)
-	d.sb.WriteString(coloredStr(cBrace, "}", cm))
+	d.closeBlock("}", depth)
 }
 
 func (d *differ) diffSlice(a, b reflect.Value, depth int) {
-	indent := strings.Repeat(d.config.Indent, depth+1)
-	closingIndent := strings.Repeat(d.config.Indent, depth)
-	cm := d.config.ColorMode
-
-	d.sb.WriteString(coloredStr(cBrace, "[\n", cm))
+	d.openBlock("[", shortType(a.Type()))
 
 	maxLen := a.Len()
 	if b.Len() > maxLen {
@@ -163,76 +294,351 @@ func (d *differ) diffSlice(a, b reflect.Value, depth int) {
 	}
 
 	for i := 0; i < maxLen; i++ {
+		indent := d.entryIndent(depth, i, maxLen)
 		aExists := i < a.Len()
 		bExists := i < b.Len()
 
 		switch {
 		case aExists && !bExists:
-			d.sb.WriteString(indent)
-			d.sb.WriteString(coloredStr(cDiffDel, fmt.Sprintf("- [%d]: %s", i, d.sprintValue(a.Index(i))), cm))
-			d.sb.WriteString("\n")
+			d.writeDel(indent, fmt.Sprintf("[%d]: %s", i, d.sprintValue(a.Index(i))))
 		case !aExists && bExists:
-			d.sb.WriteString(indent)
-			d.sb.WriteString(coloredStr(cDiffAdd, fmt.Sprintf("+ [%d]: %s", i, d.sprintValue(b.Index(i))), cm))
-			d.sb.WriteString("\n")
+			d.writeAdd(indent, fmt.Sprintf("[%d]: %s", i, d.sprintValue(b.Index(i))))
 		default:
-			aStr := d.sprintValue(a.Index(i))
-			bStr := d.sprintValue(b.Index(i))
-			if aStr == bStr {
-				d.sb.WriteString(indent)
-				d.sb.WriteString(fmt.Sprintf("[%d]: %s\n", i, aStr))
-			} else {
+			label := fmt.Sprintf("[%d]: ", i)
+			d.diffEntry(indent, label, label, a.Index(i), b.Index(i), depth)
+		}
+	}
+
+	d.closeBlock("]", depth)
+}
+
+// diffEntry writes one field, key or element present on both sides.
+// Nested structs, maps and slices of the same type are diffed
+// recursively, even when unchanged, other unchanged values are written
+// as is, and anything else becomes a -/+ pair. keyLabel is label as written for unchanged entries, which
+// may carry color.
+func (d *differ) diffEntry(indent, keyLabel, label string, a, b reflect.Value, depth int) {
+	aStr, bStr, equal := d.sprintPair(a, b)
+	if equal && !sameType(a, b) {
+		// Values that print alike but differ in type, e.g. 1 and
+		// int64(1) in an interface{}, are shown with their types.
+		d.writeChange(indent, label, a, b, withType(aStr, a), withType(bStr, b))
+		return
+	}
+	ua, ub := unwrapValue(a), unwrapValue(b)
+	// Unchanged structs, maps and long slices are written entry by
+	// entry too, so that they read like their changed siblings.
+	recurse := d.canRecurse(ua, ub, depth+1) && (!equal || hasEntries(ua, d.config))
+	if equal && !recurse {
+		d.sb.WriteString(indent)
+		d.sb.WriteString(keyLabel)
+		d.sb.WriteString(indentLines(aStr, d.cont))
+		d.sb.WriteString("\n")
+		return
+	}
+	if recurse {
+		d.sb.WriteString(indent)
+		d.sb.WriteString(keyLabel)
+		prefix := d.prefix
+		d.prefix = d.cont
+		switch ua.Kind() {
+		case reflect.Struct:
+			d.diffStruct(ua, ub, depth+1)
+		case reflect.Map:
+			d.diffMap(ua, ub, depth+1)
+		default:
+			d.diffSlice(ua, ub, depth+1)
+		}
+		d.prefix = prefix
+		if !d.config.Tree {
+			d.sb.WriteString("\n")
+		}
+		return
+	}
+	d.writeChange(indent, label, a, b, aStr, bStr)
+}
+
+// withType appends the type of v to s, its printed form, unless v is an
+// untyped nil.
+func withType(s string, v reflect.Value) string {
+	if t := dynamicType(v); t != nil {
+		return s + " (" + t.String() + ")"
+	}
+	return s
+}
+
+// hasEntries reports whether v, a struct, map, slice or array, is
+// printed with one entry per line.
+func hasEntries(v reflect.Value, c Config) bool {
+	if v.Kind() == reflect.Struct {
+		return visibleFields(v, c) > 0
+	}
+	return v.Len() > 0 && !isInlineSlice(v)
+}
+
+// canRecurse reports whether a and b, found at depth, can be diffed
+// entry by entry.
+func (d *differ) canRecurse(a, b reflect.Value, depth int) bool {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() || isLeaf(a) {
+		return false
+	}
+	if d.config.MaxDepth > 0 && depth >= d.config.MaxDepth {
+		return false
+	}
+	switch a.Kind() {
+	case reflect.Map, reflect.Slice:
+		return !a.IsNil() && !b.IsNil()
+	}
+	return true
+}
+
+func (d *differ) diffScalar(a, b reflect.Value, depth int) {
+	aStr, bStr, equal := d.sprintPair(a, b)
+	if equal {
+		d.sb.WriteString(aStr)
+	} else {
+		d.writeChange("", "", a, b, aStr, bStr)
+		// A top-level scalar diff has no closing brace; drop the final newline.
+		out := strings.TrimSuffix(d.sb.String(), "\n")
+		d.sb.Reset()
+		d.sb.WriteString(out)
+	}
+}
+
+// writeChange writes a modified entry as a -/+ line pair. label is the
+// entry prefix ("Name: ", "[2]: ", or "" for a top-level value).
+// Single-line strings get word-level highlighting of the changed parts,
+// and multi-line strings are rendered as a unified line diff.
+func (d *differ) writeChange(indent, label string, a, b reflect.Value, aStr, bStr string) {
+	sa, okA := plainString(a, aStr)
+	sb, okB := plainString(b, bStr)
+	if okA && okB {
+		if strings.Contains(sa, "\n") || strings.Contains(sb, "\n") {
+			d.writeLineDiff(indent, label, sa, sb)
+			return
+		}
+		if del, add, ok := d.highlightWords(sa, sb, true); ok {
+			d.markChanged(func() {
 				d.sb.WriteString(indent)
-				d.sb.WriteString(coloredStr(cDiffDel, fmt.Sprintf("- [%d]: %s", i, aStr), cm))
+				d.sb.WriteString(d.paint(cDiffDel, "- "+label))
+				d.sb.WriteString(del)
 				d.sb.WriteString("\n")
 				d.sb.WriteString(indent)
-				d.sb.WriteString(coloredStr(cDiffAdd, fmt.Sprintf("+ [%d]: %s", i, bStr), cm))
+				d.sb.WriteString(d.paint(cDiffAdd, "+ "+label))
+				d.sb.WriteString(add)
 				d.sb.WriteString("\n")
-			}
+			})
+			return
 		}
 	}
+	d.writeDel(indent, label+aStr)
+	d.writeAdd(indent, label+bStr)
+}
 
-	d.sb.WriteString(closingIndent)
-	d.sb.WriteString(coloredStr(cBrace, "]", cm))
+// indentLines indents every line of a multi-line value after the first.
+func indentLines(s, indent string) string {
+	if indent == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
 }
 
-func (d *differ) diffScalar(a, b reflect.Value, depth int) {
-	cm := d.config.ColorMode
-	aStr := d.sprintValue(a)
-	bStr := d.sprintValue(b)
-	if aStr == bStr {
-		d.sb.WriteString(aStr)
-	} else {
-		d.sb.WriteString(coloredStr(cDiffDel, "- "+aStr, cm))
+// writeDel writes a removed line. Lines of a multi-line text are
+// aligned under the first, past the marker.
+func (d *differ) writeDel(indent, text string) {
+	d.markChanged(func() {
+		d.sb.WriteString(indent)
+		d.sb.WriteString(d.paint(cDiffDel, "- "+indentLines(text, d.cont+"  ")))
 		d.sb.WriteString("\n")
-		d.sb.WriteString(coloredStr(cDiffAdd, "+ "+bStr, cm))
+	})
+}
+
+// writeAdd writes an added line, aligned like writeDel.
+func (d *differ) writeAdd(indent, text string) {
+	d.markChanged(func() {
+		d.sb.WriteString(indent)
+		d.sb.WriteString(d.paint(cDiffAdd, "+ "+indentLines(text, d.cont+"  ")))
+		d.sb.WriteString("\n")
+	})
+}
+
+// writeLineDiff renders two multi-line strings as unified diff hunks
+// nested under label.
+func (d *differ) writeLineDiff(indent, label, a, b string) {
+	inner := indent
+	if label != "" {
+		d.sb.WriteString(indent)
+		d.sb.WriteString(strings.TrimSuffix(label, " "))
+		d.sb.WriteString("\n")
+		inner = d.cont + d.config.Indent
+	}
+	if d.config.html {
+		a, b = blockMarks.Replace(a), blockMarks.Replace(b)
+	}
+
+	edits := diffTokens(splitLines(a), splitLines(b))
+	for _, h := range unifiedHunks(edits, diffContextLines) {
+		d.markChanged(func() {
+			d.sb.WriteString(inner)
+			d.sb.WriteString(d.paint(cType, fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.aStart+1, h.aLen, h.bStart+1, h.bLen)))
+			d.sb.WriteString("\n")
+		})
+
+		for i := 0; i < len(h.edits); {
+			if h.edits[i].op == opEqual {
+				d.sb.WriteString(inner + "  " + h.edits[i].text + "\n")
+				i++
+				continue
+			}
+			// Collect a block of deleted lines followed by inserted lines
+			// and pair them up for word-level highlighting.
+			var dels, adds []string
+			for ; i < len(h.edits) && h.edits[i].op == opDelete; i++ {
+				dels = append(dels, h.edits[i].text)
+			}
+			for ; i < len(h.edits) && h.edits[i].op == opInsert; i++ {
+				adds = append(adds, h.edits[i].text)
+			}
+			delOut := make([]string, len(dels))
+			addOut := make([]string, len(adds))
+			for j := range dels {
+				delOut[j] = d.paint(cDiffDel, dels[j])
+			}
+			for j := range adds {
+				addOut[j] = d.paint(cDiffAdd, adds[j])
+			}
+			for j := 0; j < len(dels) && j < len(adds); j++ {
+				if del, add, ok := d.highlightWords(dels[j], adds[j], false); ok {
+					delOut[j], addOut[j] = del, add
+				}
+			}
+			d.markChanged(func() {
+				for _, s := range delOut {
+					d.sb.WriteString(inner + d.paint(cDiffDel, "- ") + s + "\n")
+				}
+				for _, s := range addOut {
+					d.sb.WriteString(inner + d.paint(cDiffAdd, "+ ") + s + "\n")
+				}
+			})
+		}
 	}
 }
 
-func (d *differ) sprintValue(v reflect.Value) string {
-	noColor := Config{
-		Indent:      d.config.Indent,
-		ShowTypes:   d.config.ShowTypes,
-		UseJSONTags: d.config.UseJSONTags,
-		MaxDepth:    d.config.MaxDepth,
-		ColorMode:   false, // no color for comparison
-	}
-	return noColor.Sprint(v.Interface())
-}
-
-func (d *differ) fieldName(sf reflect.StructField) string {
-	if d.config.UseJSONTags {
-		if tag := sf.Tag.Get("json"); tag != "" {
-			parts := strings.Split(tag, ",")
-			if parts[0] == "-" {
-				return ""
+// highlightWords renders a and b with their differing words marked,
+// returning ok=false when the strings share no words worth aligning.
+// With ColorMode the changes are highlighted; otherwise they are wrapped
+// in [-removed-] and {+added+} markers. When quote is set the results
+// are rendered as quoted Go strings.
+func (d *differ) highlightWords(a, b string, quote bool) (del, add string, ok bool) {
+	edits := mergeEdits(diffTokens(splitWords(a), splitWords(b)))
+
+	for _, e := range edits {
+		if e.op == opEqual && strings.TrimSpace(e.text) != "" {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return "", "", false
+	}
+
+	text := func(s string) string {
+		if quote {
+			q := strconv.Quote(s)
+			return q[1 : len(q)-1]
+		}
+		return s
+	}
+
+	var ds, as strings.Builder
+	if quote {
+		ds.WriteString(d.paint(cDiffDel, `"`))
+		as.WriteString(d.paint(cDiffAdd, `"`))
+	}
+	for _, e := range edits {
+		switch e.op {
+		case opEqual:
+			ds.WriteString(d.paint(cDiffDel, text(e.text)))
+			as.WriteString(d.paint(cDiffAdd, text(e.text)))
+		case opDelete:
+			if d.config.ColorMode {
+				ds.WriteString(d.paint(cDiffDelHi, text(e.text)))
+			} else {
+				ds.WriteString("[-" + text(e.text) + "-]")
 			}
-			if parts[0] != "" {
-				return parts[0]
+		case opInsert:
+			if d.config.ColorMode {
+				as.WriteString(d.paint(cDiffAddHi, text(e.text)))
+			} else {
+				as.WriteString("{+" + text(e.text) + "+}")
 			}
 		}
 	}
-	return sf.Name
+	if quote {
+		ds.WriteString(d.paint(cDiffDel, `"`))
+		as.WriteString(d.paint(cDiffAdd, `"`))
+	}
+	return ds.String(), as.String(), true
+}
+
+// mergeEdits joins adjacent edits with the same op.
+func mergeEdits(edits []edit) []edit {
+	var merged []edit
+	for _, e := range edits {
+		if n := len(merged); n > 0 && merged[n-1].op == e.op {
+			merged[n-1].text += e.text
+			continue
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// plainString returns the string held by v if it was rendered as a plain
+// quoted string (i.e. not through a custom printer), unwrapping
+// interfaces and pointers.
+func plainString(v reflect.Value, rendered string) (string, bool) {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() != reflect.String {
+		return "", false
+	}
+	s := v.String()
+	if rendered != strconv.Quote(s) {
+		return "", false
+	}
+	return s, true
+}
+
+func (d *differ) sprintValue(v reflect.Value) string {
+	noColor := d.config
+	noColor.ColorMode = false // no color for comparison
+	noColor.Rules = nil
+	return noColor.sprintNamed(d.name, interfaceOf(v))
+}
+
+// sprintPair renders a and b for display and reports whether they are
+// equal. Values are compared in full and unmasked, since MaxDepth
+// summaries or masks of different values can be the same; summaries
+// are then shown in full.
+func (d *differ) sprintPair(a, b reflect.Value) (string, string, bool) {
+	aStr, bStr := d.sprintValue(a), d.sprintValue(b)
+	if d.config.MaxDepth == 0 && !d.config.MaskSecrets {
+		return aStr, bStr, aStr == bStr
+	}
+	cmp := differ{config: d.config, name: d.name}
+	cmp.config.MaxDepth = 0
+	cmp.config.MaskSecrets = false
+	switch {
+	case cmp.sprintValue(a) == cmp.sprintValue(b):
+		return aStr, bStr, true
+	case aStr == bStr && d.config.MaxDepth > 0:
+		full := differ{config: d.config, name: d.name}
+		full.config.MaxDepth = 0
+		return full.sprintValue(a), full.sprintValue(b), false
+	}
+	return aStr, bStr, false
 }
 
 func (d *differ) writeLine(prefix, text string) {
@@ -0,0 +1,263 @@
+package pf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// envSettings maps environment variables to config file settings.
+var envSettings = []struct{ env, name string }{
+	{"PF_INDENT", "indent"},
+	{"PF_DEPTH", "depth"},
+	{"PF_COLOR", "color"},
+	{"PF_THEME", "theme"},
+	{"PF_JSON_TAGS", "json_tags"},
+	{"PF_WIDTH", "width"},
+	{"PF_MASK_SECRETS", "mask_secrets"},
+	{"PF_EMBEDDED", "embedded"},
+}
+
+func init() {
+	// Nothing can print yet, so DefaultConfig can be changed safely.
+	// Invalid settings are skipped; LoadEnv reports them.
+	DefaultConfig, _ = loadEnv(DefaultConfig, os.Getenv)
+}
+
+// LoadEnv updates the default config (see SetDefault) from the config
+// file and the environment, which override it in that order. Settings
+// that are not given are left as they are. It runs when the package is
+// initialized; call it to apply later changes or to see errors.
+//
+//	PF_INDENT        indent per level: a number of spaces, "tab" or a string
+//	PF_DEPTH         MaxDepth
+//	PF_COLOR         ColorMode: true/false, 1/0, on/off, yes/no, always/never
+//	PF_THEME         a theme: default, light, high-contrast or one from the file
+//	PF_JSON_TAGS     UseJSONTags
+//	PF_WIDTH         Width
+//	PF_MASK_SECRETS  MaskSecrets
+//	PF_EMBEDDED      Embedded: nested, flatten or mark
+//	PF_CONFIG        the config file (default $XDG_CONFIG_HOME/pf/config or
+//	                 ~/.config/pf/config)
+//
+// NO_COLOR, when set and PF_COLOR is not, turns colors off. The config
+// file takes the same settings without the PF_ prefix, in lower case,
+// and defines themes in sections:
+//
+//	# ~/.config/pf/config
+//	indent = 4
+//	theme = mine
+//
+//	[theme.mine]
+//	key = bold blue
+//	string = green
+//	number = 38;5;208
+//
+// Theme colors are SGR parameters (see Theme) or the words bold, dim,
+// italic, underline, black, red, green, yellow, blue, magenta, cyan,
+// white, gray and bright-red through bright-white.
+func LoadEnv() error {
+	c, err := loadEnv(Default(), os.Getenv)
+	SetDefault(c)
+	return err
+}
+
+// loadEnv applies the config file and the environment read by getenv
+// to c, skipping and reporting invalid settings.
+func loadEnv(c Config, getenv func(string) string) (Config, error) {
+	var errs []error
+	themes := make(map[string]Theme)
+	for name, t := range builtinThemes {
+		themes[name] = t
+	}
+
+	path := getenv("PF_CONFIG")
+	if path == "" {
+		path = defaultConfigPath(getenv)
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		settings, err := parseConfigFile(path, string(data), themes)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, s := range settings {
+			if err := applySetting(&c, s.name, s.value, themes); err != nil {
+				errs = append(errs, fmt.Errorf("pf: %s:%d: %w", path, s.line, err))
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) && path != "" {
+		errs = append(errs, fmt.Errorf("pf: %w", err))
+	}
+
+	if getenv("NO_COLOR") != "" && getenv("PF_COLOR") == "" {
+		c.ColorMode = false
+	}
+	for _, e := range envSettings {
+		if v := getenv(e.env); v != "" {
+			if err := applySetting(&c, e.name, v, themes); err != nil {
+				errs = append(errs, fmt.Errorf("pf: %s: %w", e.env, err))
+			}
+		}
+	}
+	return c, errors.Join(errs...)
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/pf/config, or
+// ~/.config/pf/config, or "" if neither directory is known.
+func defaultConfigPath(getenv func(string) string) string {
+	if dir := getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "pf", "config")
+	}
+	if home := getenv("HOME"); home != "" {
+		return filepath.Join(home, ".config", "pf", "config")
+	}
+	return ""
+}
+
+// setting is a "name = value" line of the config file.
+type setting struct {
+	name, value string
+	line        int
+}
+
+// parseConfigFile returns the settings in data and adds its themes to
+// themes.
+func parseConfigFile(path, data string, themes map[string]Theme) ([]setting, error) {
+	var settings []setting
+	var errs []error
+	theme := "" // the theme section being read
+	for i, line := range strings.Split(data, "\n") {
+		fail := func(format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("pf: %s:%d: %s", path, i+1, fmt.Sprintf(format, args...)))
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section := strings.TrimSpace(line[1 : len(line)-1])
+			name, ok := strings.CutPrefix(section, "theme.")
+			if !ok || name == "" {
+				fail("unknown section %q", section)
+				theme = "-"
+				continue
+			}
+			theme = name
+			themes[theme] = Theme{}
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			fail("expected name = value")
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			if s, err := strconv.Unquote(value); err == nil {
+				value = s
+			}
+		}
+		switch theme {
+		case "":
+			settings = append(settings, setting{name, value, i + 1})
+		case "-":
+			// Inside an unknown section, already reported.
+		default:
+			t := themes[theme]
+			if err := setThemeColor(&t, name, value); err != nil {
+				fail("%v", err)
+			}
+			themes[theme] = t
+		}
+	}
+	return settings, errors.Join(errs...)
+}
+
+func setThemeColor(t *Theme, name, value string) error {
+	fields := map[string]*string{
+		"key": &t.Key, "string": &t.String, "number": &t.Number,
+		"bool": &t.Bool, "type": &t.Type, "nil": &t.Nil, "brace": &t.Brace,
+		"deleted": &t.Deleted, "added": &t.Added,
+	}
+	field, ok := fields[name]
+	if !ok {
+		return fmt.Errorf("unknown theme color %q", name)
+	}
+	sgr, err := parseColor(value)
+	if err != nil {
+		return err
+	}
+	*field = sgr
+	return nil
+}
+
+// applySetting sets the config field named by a config file setting.
+func applySetting(c *Config, name, value string, themes map[string]Theme) error {
+	invalid := fmt.Errorf("invalid %s %q", name, value)
+	switch name {
+	case "indent":
+		switch n, err := strconv.Atoi(value); {
+		case err == nil && n >= 0:
+			c.Indent = strings.Repeat(" ", n)
+		case value == "tab" || value == `\t`:
+			c.Indent = "\t"
+		default:
+			c.Indent = value
+		}
+	case "depth", "width":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return invalid
+		}
+		if name == "depth" {
+			c.MaxDepth = n
+		} else {
+			c.Width = n
+		}
+	case "color", "json_tags", "mask_secrets":
+		b, ok := parseSwitch(value)
+		if !ok {
+			return invalid
+		}
+		switch name {
+		case "color":
+			c.ColorMode = b
+		case "json_tags":
+			c.UseJSONTags = b
+		default:
+			c.MaskSecrets = b
+		}
+	case "embedded":
+		modes := map[string]EmbedMode{"nested": EmbedNested, "flatten": EmbedFlatten, "mark": EmbedMark}
+		mode, ok := modes[strings.ToLower(value)]
+		if !ok {
+			return invalid
+		}
+		c.Embedded = mode
+	case "theme":
+		t, ok := themes[value]
+		if !ok {
+			return fmt.Errorf("unknown theme %q", value)
+		}
+		c.Theme = t
+	default:
+		return fmt.Errorf("unknown setting %q", name)
+	}
+	return nil
+}
+
+// parseSwitch parses an on/off setting.
+func parseSwitch(s string) (value, ok bool) {
+	switch strings.ToLower(s) {
+	case "1", "t", "true", "on", "yes", "always":
+		return true, true
+	case "0", "f", "false", "off", "no", "never":
+		return false, true
+	}
+	return false, false
+}
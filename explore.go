@@ -0,0 +1,571 @@
+package pf
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nd-forge/pf/internal/term"
+)
+
+// Explore shows v in the terminal as a tree whose nodes can be expanded
+// and collapsed, for values too large to read as a whole. It returns
+// when the user quits, or an error if stdin is not a terminal.
+//
+//	↑ ↓ j k       move            /      search names and values
+//	→ l           expand          n N    next or previous match
+//	← h           collapse        :      jump to a path (see Select)
+//	Enter Space   toggle          y      copy as pf, JSON or Go syntax
+//	g G           first or last   q      quit
+//
+// Copying uses the OSC 52 escape sequence, which most terminals pass to
+// the system clipboard.
+func (c Config) Explore(v interface{}) error {
+	restore, err := term.MakeRaw(os.Stdin.Fd())
+	if err != nil {
+		return fmt.Errorf("pf: Explore needs a terminal: %w", err)
+	}
+	defer restore()
+	size := func() (int, int) {
+		if cols, rows, ok := term.Size(os.Stdout.Fd()); ok && rows > 0 {
+			return cols, rows
+		}
+		return 80, 24
+	}
+	return c.explore(v, os.Stdin, os.Stdout, size)
+}
+
+// explore runs the explorer, reading keys from in and drawing on out in
+// the terminal's alternate screen. size reports the screen size, which
+// is checked before each redraw.
+func (c Config) explore(v interface{}, in io.Reader, out io.Writer, size func() (cols, rows int)) error {
+	e := newExplorer(c, v)
+	r := bufio.NewReader(in)
+	io.WriteString(out, "\033[?1049h\033[?25l")
+	defer io.WriteString(out, "\033[?25h\033[?1049l")
+	for {
+		e.width, e.height = size()
+		if _, err := io.WriteString(out, e.view()); err != nil {
+			return err
+		}
+		key, err := readKey(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if e.handle(key) {
+			return nil
+		}
+		if e.clip != "" {
+			// OSC 52: set the clipboard to the base64 text.
+			io.WriteString(out, "\033]52;c;"+base64.StdEncoding.EncodeToString([]byte(e.clip))+"\a")
+			e.clip = ""
+		}
+	}
+}
+
+// readKey reads one key press, returning the character typed or a name
+// such as "up", "enter" or "ctrl-c". Unknown escape sequences are
+// returned as "".
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 3:
+		return "ctrl-c", nil
+	case '\r', '\n':
+		return "enter", nil
+	case 8, 127:
+		return "backspace", nil
+	case 27:
+		// A lone Esc arrives without the rest of a sequence.
+		if r.Buffered() == 0 {
+			return "esc", nil
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			return "esc", nil
+		}
+		var seq []byte
+		for {
+			c, err := r.ReadByte()
+			if err != nil {
+				return "", err
+			}
+			seq = append(seq, c)
+			if c >= 0x40 && c <= 0x7e {
+				break
+			}
+		}
+		switch string(seq) {
+		case "A":
+			return "up", nil
+		case "B":
+			return "down", nil
+		case "C":
+			return "right", nil
+		case "D":
+			return "left", nil
+		case "H", "1~", "7~":
+			return "home", nil
+		case "F", "4~", "8~":
+			return "end", nil
+		case "5~":
+			return "pgup", nil
+		case "6~":
+			return "pgdn", nil
+		}
+		return "", nil
+	}
+	if b < utf8.RuneSelf {
+		return string(b), nil
+	}
+	r.UnreadByte()
+	ch, _, err := r.ReadRune()
+	return string(ch), err
+}
+
+// explorer is the state of an Explore session.
+type explorer struct {
+	config Config
+	value  interface{}
+	root   *node
+	// rows holds the nodes shown, those whose ancestors are expanded.
+	rows   []*node
+	cursor int
+	// top is the index of the first row on screen.
+	top           int
+	width, height int
+	// prompt is "/" or ":" while a search or path is typed into input,
+	// and "y" while asking for a copy format.
+	prompt string
+	input  string
+	search string
+	// status is a message shown until the next key.
+	status string
+	// clip holds text to copy to the clipboard.
+	clip string
+}
+
+// node is a value in the explorer's tree.
+type node struct {
+	child
+	parent   *node
+	depth    int
+	kids     []*node
+	loaded   bool
+	expanded bool
+}
+
+func newExplorer(c Config, v interface{}) *explorer {
+	e := &explorer{config: c, value: v, width: 80, height: 24}
+	e.root = &node{child: child{value: reflect.ValueOf(v)}, expanded: true}
+	e.refresh()
+	return e
+}
+
+// children returns the fields, keys or elements of n, loading them the
+// first time. A value already shown above n, through a pointer cycle,
+// has none.
+func (e *explorer) children(n *node) []*node {
+	if n.loaded {
+		return n.kids
+	}
+	n.loaded = true
+	if n.cycle() {
+		return nil
+	}
+	s := selector{config: e.config}
+	for _, c := range s.children(n.value) {
+		n.kids = append(n.kids, &node{child: c, parent: n, depth: n.depth + 1})
+	}
+	return n.kids
+}
+
+// cycle reports whether n is a pointer to the value of one of its
+// ancestors.
+func (n *node) cycle() bool {
+	p, ok := pointerOf(n.value)
+	if !ok {
+		return false
+	}
+	for a := n.parent; a != nil; a = a.parent {
+		if q, ok := pointerOf(a.value); ok && q == p {
+			return true
+		}
+	}
+	return false
+}
+
+func pointerOf(v reflect.Value) (uintptr, bool) {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() {
+		return 0, false
+	}
+	return v.Pointer(), true
+}
+
+// chain returns the children leading from the root to n.
+func (n *node) chain() []child {
+	var out []child
+	for ; n.parent != nil; n = n.parent {
+		out = append([]child{n.child}, out...)
+	}
+	return out
+}
+
+func (n *node) path() Path {
+	var p Path
+	for _, c := range n.chain() {
+		p = append(p, c.elem)
+	}
+	return p
+}
+
+// refresh rebuilds the visible rows, keeping the cursor on its node.
+func (e *explorer) refresh() {
+	var current *node
+	if e.cursor < len(e.rows) {
+		current = e.rows[e.cursor]
+	}
+	e.rows = e.rows[:0]
+	var add func(n *node)
+	add = func(n *node) {
+		e.rows = append(e.rows, n)
+		if n.expanded {
+			for _, k := range e.children(n) {
+				add(k)
+			}
+		}
+	}
+	add(e.root)
+	e.moveTo(current)
+}
+
+// moveTo puts the cursor on n if it is shown.
+func (e *explorer) moveTo(n *node) {
+	for i, r := range e.rows {
+		if r == n {
+			e.cursor = i
+			return
+		}
+	}
+}
+
+// reveal expands the ancestors of n and moves the cursor to it.
+func (e *explorer) reveal(n *node) {
+	for a := n.parent; a != nil; a = a.parent {
+		a.expanded = true
+	}
+	e.refresh()
+	e.moveTo(n)
+}
+
+// handle applies a key, reporting whether the explorer should quit.
+func (e *explorer) handle(key string) bool {
+	if e.prompt != "" {
+		e.handlePrompt(key)
+		return false
+	}
+	e.status = ""
+	n := e.rows[e.cursor]
+	switch key {
+	case "q", "ctrl-c":
+		return true
+	case "up", "k":
+		e.cursor--
+	case "down", "j":
+		e.cursor++
+	case "pgup":
+		e.cursor -= e.pageSize()
+	case "pgdn":
+		e.cursor += e.pageSize()
+	case "home", "g":
+		e.cursor = 0
+	case "end", "G":
+		e.cursor = len(e.rows) - 1
+	case "right", "l":
+		if len(e.children(n)) > 0 {
+			if n.expanded {
+				e.cursor++
+			} else {
+				n.expanded = true
+				e.refresh()
+			}
+		}
+	case "left", "h":
+		if n.expanded && n.parent != nil {
+			n.expanded = false
+			e.refresh()
+		} else if n.parent != nil {
+			e.moveTo(n.parent)
+		}
+	case "enter", " ":
+		if len(e.children(n)) > 0 && n.parent != nil {
+			n.expanded = !n.expanded
+			e.refresh()
+		}
+	case "/", ":", "y":
+		e.prompt, e.input = key, ""
+	case "n":
+		e.find(1)
+	case "N":
+		e.find(-1)
+	}
+	e.cursor = max(0, min(e.cursor, len(e.rows)-1))
+	return false
+}
+
+func (e *explorer) handlePrompt(key string) {
+	if e.prompt == "y" {
+		e.prompt = ""
+		e.copy(key)
+		return
+	}
+	switch key {
+	case "enter":
+		prompt, input := e.prompt, e.input
+		e.prompt = ""
+		if prompt == "/" {
+			e.search = input
+			e.find(1)
+		} else {
+			e.jump(input)
+		}
+	case "esc", "ctrl-c":
+		e.prompt = ""
+	case "backspace":
+		if e.input != "" {
+			_, size := utf8.DecodeLastRuneInString(e.input)
+			e.input = e.input[:len(e.input)-size]
+		}
+	default:
+		if utf8.RuneCountInString(key) == 1 {
+			e.input += key
+		}
+	}
+}
+
+// find moves to the next node after the cursor, or the previous one if
+// dir is negative, whose name or value contains the search text,
+// ignoring case. Collapsed nodes are searched too.
+func (e *explorer) find(dir int) {
+	if e.search == "" {
+		return
+	}
+	var all []*node
+	var add func(n *node)
+	add = func(n *node) {
+		all = append(all, n)
+		for _, k := range e.children(n) {
+			add(k)
+		}
+	}
+	add(e.root)
+
+	start := 0
+	for i, n := range all {
+		if n == e.rows[e.cursor] {
+			start = i
+		}
+	}
+	search := strings.ToLower(e.search)
+	for i := 1; i <= len(all); i++ {
+		n := all[((start+dir*i)%len(all)+len(all))%len(all)]
+		if strings.Contains(strings.ToLower(stripANSI(e.text(n))), search) {
+			e.reveal(n)
+			return
+		}
+	}
+	e.status = fmt.Sprintf("no match for %q", e.search)
+}
+
+// jump moves to the first value matching query (see Select).
+func (e *explorer) jump(query string) {
+	matches, err := e.config.Select(e.value, query)
+	if err != nil {
+		e.status = err.Error()
+		return
+	}
+	if len(matches) == 0 {
+		e.status = fmt.Sprintf("no match for %q", query)
+		return
+	}
+	n := e.root
+	for _, elem := range matches[0].Path {
+		want := Path{elem}.String()
+		for _, k := range e.children(n) {
+			if (Path{k.elem}).String() == want {
+				n = k
+				break
+			}
+		}
+	}
+	e.reveal(n)
+}
+
+// copy puts the value at the cursor on the clipboard in the format
+// chosen by key: p for pf, j for JSON or g for Go syntax.
+func (e *explorer) copy(key string) {
+	n := e.rows[e.cursor]
+	if e.hasCycle(n) {
+		e.status = "cannot copy a value with a pointer cycle"
+		return
+	}
+	v := interfaceOf(n.value)
+	plain := e.config
+	plain.ColorMode = false
+	var format string
+	switch key {
+	case "p":
+		format = "pf"
+		e.clip = plain.Sprint(v)
+	case "j":
+		format = "JSON"
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			e.status = err.Error()
+			return
+		}
+		e.clip = string(b)
+	case "g":
+		format = "Go syntax"
+		plain.GoSyntax = true
+		e.clip = plain.Sprint(v)
+	default:
+		return
+	}
+	where := n.path().String()
+	if where == "" {
+		where = "value"
+	}
+	e.status = fmt.Sprintf("copied %s as %s", where, format)
+}
+
+// hasCycle reports whether n or a value below it points back to n or
+// one of its ancestors, which cannot be printed in full.
+func (e *explorer) hasCycle(n *node) bool {
+	if n.cycle() {
+		return true
+	}
+	for _, k := range e.children(n) {
+		if e.hasCycle(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// text renders n's name and value on one line. Structs, maps and
+// slices show as a summary such as User{…5 fields}; anything else is
+// printed in full.
+func (e *explorer) text(n *node) string {
+	f := e.config.newFormatter()
+	f.singleLine = true
+	switch n.elem.Kind {
+	case FieldElem:
+		f.colored(cKey, n.elem.Name)
+		f.sb.WriteString(": ")
+	case KeyElem:
+		f.inKey = true
+		f.format(reflect.ValueOf(n.elem.Key), 0)
+		f.inKey = false
+		f.sb.WriteString(": ")
+	case IndexElem:
+		f.colored(cKey, fmt.Sprintf("[%d]", n.elem.Index))
+		f.sb.WriteString(": ")
+	}
+	f.path = n.chain()
+	if !f.formatSummary(n.value, false) {
+		f.format(n.value, 0)
+	}
+	return f.sb.String()
+}
+
+// pageSize is the number of rows shown above the status line.
+func (e *explorer) pageSize() int {
+	return max(1, e.height-1)
+}
+
+// lines returns the rows on screen and the status line.
+func (e *explorer) lines() []string {
+	h := e.pageSize()
+	if e.cursor < e.top {
+		e.top = e.cursor
+	}
+	if e.cursor >= e.top+h {
+		e.top = e.cursor - h + 1
+	}
+
+	open, closed := "▾ ", "▸ "
+	if e.config.ASCII {
+		open, closed = "- ", "+ "
+	}
+	var out []string
+	for i := e.top; i < len(e.rows) && i < e.top+h; i++ {
+		n := e.rows[i]
+		marker := "  "
+		if len(e.children(n)) > 0 && n.parent != nil {
+			marker = closed
+			if n.expanded {
+				marker = open
+			}
+		}
+		line := strings.Repeat("  ", n.depth) + marker + e.text(n)
+		switch {
+		case i == e.cursor:
+			// The cursor row is shown in reverse video, without colors.
+			line = "\033[7m" + padRight(truncateWidth(stripANSI(line), e.width), e.width) + cReset
+		case displayWidth(stripANSI(line)) > e.width:
+			line = truncateWidth(stripANSI(line), e.width)
+		}
+		out = append(out, line)
+	}
+
+	var status string
+	switch {
+	case e.prompt == "y":
+		status = "copy as (p)f, (j)son or (g)o syntax"
+	case e.prompt != "":
+		status = e.prompt + e.input
+	case e.status != "":
+		status = e.status
+	default:
+		status = e.rows[e.cursor].path().String()
+		if status == "" {
+			status = "."
+		}
+		status += "  (/ search, : jump, y copy, q quit)"
+	}
+	return append(out, truncateWidth(status, e.width))
+}
+
+// view returns the escape sequences redrawing the screen.
+func (e *explorer) view() string {
+	lines := e.lines()
+	status := lines[len(lines)-1]
+	for len(lines) <= e.pageSize() {
+		lines = append(lines[:len(lines)-1], "", status)
+	}
+	var sb strings.Builder
+	sb.WriteString("\033[H")
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString(line)
+		sb.WriteString("\033[K")
+	}
+	return sb.String()
+}
@@ -0,0 +1,286 @@
+package pf
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// EmbedMode is how the fields of embedded structs are printed.
+type EmbedMode int
+
+const (
+	// EmbedNested prints an embedded struct as a field named after its
+	// type, e.g. Base: { ID: 1 }.
+	EmbedNested EmbedMode = iota
+	// EmbedFlatten promotes the fields of embedded structs into the
+	// parent, as Go does: a field hides the fields of the same name in
+	// deeper embedded structs, and names that are ambiguous at the same
+	// depth are dropped.
+	EmbedFlatten
+	// EmbedMark prints embedded structs nested, marked as such, e.g.
+	// <embedded Base>: { ID: 1 }.
+	EmbedMark
+)
+
+// structField is a printed field of a struct type.
+type structField struct {
+	name string
+	// index is the field's index sequence, as for FieldByIndex, which
+	// goes through embedded structs for promoted fields.
+	index []int
+	// tagged is set when the name comes from a json tag.
+	tagged bool
+	// omitEmpty, omitZero and quoted are the json tag options omitempty,
+	// omitzero and string.
+	omitEmpty, omitZero, quoted bool
+}
+
+// tagField returns sf with the name and options of its json tag, or
+// false if encoding/json ignores it because of a "-" tag. Invalid tag
+// names are ignored, as encoding/json does.
+func tagField(sf reflect.StructField, index []int) (structField, bool) {
+	field := structField{name: sf.Name, index: index}
+	tag := sf.Tag.Get("json")
+	if tag == "-" {
+		return field, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if isValidTag(name) {
+		field.name, field.tagged = name, true
+	}
+	field.omitEmpty = hasOption(opts, "omitempty")
+	field.omitZero = hasOption(opts, "omitzero")
+	if hasOption(opts, "string") {
+		t := sf.Type
+		if t.Kind() == reflect.Ptr && t.Name() == "" {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Bool, reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64:
+			field.quoted = true
+		}
+	}
+	return field, true
+}
+
+// isValidTag reports whether name can be a json field name.
+func isValidTag(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", r) && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldName returns the name printed for sf, or "" if it is not
+// printed. Promoted fields are named by their own struct field.
+func (c Config) fieldName(sf reflect.StructField) string {
+	if !c.UseJSONTags {
+		return sf.Name
+	}
+	field, ok := tagField(sf, nil)
+	if !ok {
+		return ""
+	}
+	return field.name
+}
+
+// structFields returns the fields of struct type t that are printed,
+// in order. With UseJSONTags they are the fields encoding/json would
+// encode, otherwise the exported fields, flattened or marked according
+// to Embedded. Go syntax needs the Go field names, nested.
+func (c Config) structFields(t reflect.Type) []structField {
+	useJSON := c.UseJSONTags && !c.GoSyntax
+	if !useJSON && (c.Embedded != EmbedFlatten || c.GoSyntax) {
+		var fields []structField
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name := sf.Name
+			if sf.Anonymous && c.Embedded == EmbedMark && !c.GoSyntax {
+				name = "<embedded " + name + ">"
+			}
+			fields = append(fields, structField{name: name, index: []int{i}})
+		}
+		return fields
+	}
+
+	// Walk the embedded structs breadth first, like encoding/json, so
+	// that shallower fields are found first and hide deeper ones.
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var fields []structField
+	hidden := make(map[string]bool)
+	visited := make(map[reflect.Type]bool)
+	for next := []embedded{{t, nil}}; len(next) > 0; {
+		current := next
+		next = nil
+		level := make(map[reflect.Type]bool)
+		byName := make(map[string][]structField)
+		var names []string
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			level[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				index := append(append([]int(nil), e.index...), i)
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr && ft.Name() == "" {
+					ft = ft.Elem()
+				}
+				// Embedded unexported structs may have exported fields.
+				if !sf.IsExported() && (!sf.Anonymous || ft.Kind() != reflect.Struct) {
+					continue
+				}
+				field := structField{name: sf.Name, index: index}
+				if useJSON {
+					var ok bool
+					if field, ok = tagField(sf, index); !ok {
+						continue
+					}
+				}
+				if sf.Anonymous && ft.Kind() == reflect.Struct && !field.tagged {
+					next = append(next, embedded{ft, index})
+					continue
+				}
+				if hidden[field.name] {
+					continue
+				}
+				if byName[field.name] == nil {
+					names = append(names, field.name)
+				}
+				byName[field.name] = append(byName[field.name], field)
+			}
+		}
+		for typ := range level {
+			visited[typ] = true
+		}
+		for _, name := range names {
+			hidden[name] = true
+			if field, ok := dominantField(byName[name], useJSON); ok {
+				fields = append(fields, field)
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// dominantField returns the field of a name among those at the same
+// depth: the only one, or with json tags, the only tagged one.
+func dominantField(fields []structField, useJSON bool) (structField, bool) {
+	if len(fields) == 1 {
+		return fields[0], true
+	}
+	if !useJSON {
+		return structField{}, false
+	}
+	var tagged []structField
+	for _, f := range fields {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return structField{}, false
+}
+
+// hasOption reports whether the comma-separated tag options include opt.
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the field in struct v, or false if it is promoted
+// through a nil embedded pointer. A field with the string option is
+// returned as the string encoding/json writes for it, e.g. "5".
+func (sf structField) value(v reflect.Value) (reflect.Value, bool) {
+	fv, err := v.FieldByIndexErr(sf.index)
+	if err != nil {
+		return fv, false
+	}
+	if sf.quoted && fv.CanInterface() && !(fv.Kind() == reflect.Ptr && fv.IsNil()) {
+		if b, err := json.Marshal(fv.Interface()); err == nil {
+			return reflect.ValueOf(string(b)), true
+		}
+	}
+	return fv, true
+}
+
+// omitted reports whether the field, whose value is v, is left out by
+// encoding/json because of omitempty or omitzero.
+func (sf structField) omitted(v reflect.Value) bool {
+	return sf.omitEmpty && isEmptyValue(v) || sf.omitZero && isZeroValue(v)
+}
+
+// isEmptyValue reports whether v is empty for omitempty: false, 0, a nil
+// pointer or interface, or an empty string, slice, map or array.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Ptr:
+		return v.IsZero()
+	}
+	return false
+}
+
+// isZeroer is implemented by types such as time.Time that tell
+// omitzero when they are zero.
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// isZeroValue reports whether v is zero for omitzero: by its IsZero
+// method if it has one, or else reflect's zero value.
+func isZeroValue(v reflect.Value) bool {
+	t := v.Type()
+	switch {
+	case (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) && v.IsNil():
+		return true
+	case !v.CanInterface():
+	case t.Implements(isZeroerType):
+		return v.Interface().(isZeroer).IsZero()
+	case reflect.PointerTo(t).Implements(isZeroerType):
+		if !v.CanAddr() {
+			c := reflect.New(t).Elem()
+			c.Set(v)
+			v = c
+		}
+		return v.Addr().Interface().(isZeroer).IsZero()
+	}
+	return v.IsZero()
+}
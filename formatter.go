@@ -7,40 +7,91 @@ import (
 )
 
 func (f *formatter) format(v reflect.Value, depth int) {
-	if f.config.MaxDepth > 0 && depth > f.config.MaxDepth {
-		f.sb.WriteString("...")
-		return
+	action := f.ruleAction(v)
+	if action == Expand {
+		f.expanded++
+		defer func() { f.expanded-- }()
 	}
+	// Structs, maps and slices at MaxDepth are summarized, since their
+	// entries would be past it. Collapse rules have their own summary.
+	summarize := action != Collapse && f.config.MaxDepth > 0 && depth >= f.config.MaxDepth && f.expanded == 0 && !f.leadsToExpand()
 
 	// Handle invalid (nil interface)
 	if !v.IsValid() {
 		f.colored(cNil, "nil")
 		return
 	}
+	if f.maskSecret(v) {
+		return
+	}
 
 	// Check interfaces BEFORE dereferencing pointers,
 	// so pointer receivers work too.
-	if f.tryInterfaces(v) {
+	if f.tryInterfaces(v, depth) {
 		return
 	}
 
 	// Dereference pointers
+	isPtr := false
 	for v.Kind() == reflect.Ptr {
 		if v.IsNil() {
-			f.colored(cNil, "nil")
+			switch {
+			case f.config.GoSyntax:
+				f.colored(cNil, "("+v.Type().String()+")(nil)")
+			case summarize:
+				f.colored(cNil, shortType(v.Type())+"(nil)")
+			default:
+				f.colored(cNil, "nil")
+			}
 			return
 		}
 		v = v.Elem()
+		isPtr = true
+		if f.config.GoSyntax && isCompositeKind(v.Kind()) {
+			f.sb.WriteString("&")
+		}
 		// Check interfaces again on the dereferenced value
-		if f.tryInterfaces(v) {
+		if f.tryInterfaces(v, depth) {
+			return
+		}
+	}
+
+	if action == Collapse {
+		if c := unwrapValue(v); c.IsValid() && isCompositeKind(c.Kind()) {
+			f.formatCollapsed(c)
 			return
 		}
 	}
 
+	// Short slices of scalars print inline, so their elements add no
+	// depth.
+	if isInlineSlice(unwrapValue(v)) {
+		summarize = false
+	}
+	if summarize && f.formatSummary(v, isPtr && !f.config.GoSyntax) {
+		return
+	}
+
 	f.formatByKind(v, depth)
 }
 
 func (f *formatter) formatByKind(v reflect.Value, depth int) {
+	if f.config.TableSlices && !f.singleLine && !f.config.GoSyntax && !f.config.Tree {
+		if t, ok := f.buildTable(v); ok {
+			f.writeTable(t)
+			return
+		}
+	}
+	if f.config.Width > 0 && !f.singleLine && !f.tree() && isCompositeKind(v.Kind()) && f.tryCompact(v, depth) {
+		return
+	}
+	if f.config.GoSyntax && isSimpleKind(v.Kind()) && v.Type().PkgPath() != "" {
+		// Named basic type: render as a conversion, e.g. pf.Status(1).
+		f.colored(cType, v.Type().String())
+		f.sb.WriteString("(")
+		defer f.sb.WriteString(")")
+	}
+
 	switch v.Kind() {
 	case reflect.Struct:
 		f.formatStruct(v, depth)
@@ -64,19 +115,114 @@ func (f *formatter) formatByKind(v reflect.Value, depth int) {
 		} else {
 			f.format(v.Elem(), depth)
 		}
-	case reflect.Chan:
-		f.colored(cType, fmt.Sprintf("(chan %s)", v.Type().Elem()))
-	case reflect.Func:
-		f.colored(cType, fmt.Sprintf("(func %s)", v.Type()))
+	case reflect.Chan, reflect.Func:
+		if f.config.GoSyntax {
+			f.colored(cType, fmt.Sprintf("(%s)(%#x)", v.Type(), v.Pointer()))
+		} else if v.Kind() == reflect.Chan {
+			f.colored(cType, fmt.Sprintf("(chan %s)", v.Type().Elem()))
+		} else {
+			f.colored(cType, fmt.Sprintf("(func %s)", v.Type()))
+		}
 	default:
 		f.sb.WriteString(fmt.Sprintf("%v", v.Interface()))
 	}
 }
 
+// tryCompact writes v on a single line if it fits within Config.Width
+// from the current column. Returns true if it did.
+func (f *formatter) tryCompact(v reflect.Value, depth int) bool {
+	return f.writeCompact(func(sub *formatter) { sub.formatByKind(v, depth) })
+}
+
+// writeCompact runs write on a single-line formatter and writes its
+// output if it fits within Config.Width. Returns true if it did.
+func (f *formatter) writeCompact(write func(sub *formatter)) bool {
+	sub := &formatter{config: f.config, singleLine: true, rules: f.rules, path: f.path, name: f.name, expanded: f.expanded, inKey: f.inKey}
+	write(sub)
+	line := sub.sb.String()
+	if strings.Contains(line, "\n") {
+		return false
+	}
+
+	out := f.sb.String()
+	col := displayWidth(stripANSI(out[strings.LastIndexByte(out, '\n')+1:]))
+	if col+displayWidth(stripANSI(line)) > f.config.Width {
+		return false
+	}
+	f.sb.WriteString(line)
+	return true
+}
+
+// openBlock writes an opening brace and, unless formatting on a single
+// line, the newline before the first entry. Trees have no braces.
+func (f *formatter) openBlock(brace string) {
+	if f.tree() {
+		return
+	}
+	if f.singleLine {
+		f.colored(cBrace, brace)
+	} else {
+		if f.config.html {
+			f.sb.WriteString(blockStart)
+		}
+		f.colored(cBrace, brace+"\n")
+	}
+}
+
+// entryIndent writes the indentation of entry i of n at depth+1, or in
+// a tree, the branches leading to it.
+func (f *formatter) entryIndent(depth, i, n int) {
+	if f.tree() {
+		for len(f.branches) < depth {
+			f.branches = append(f.branches, "    ")
+		}
+		f.branches = f.branches[:depth]
+		branch, cont := treeBranch(i == n-1, f.config.ASCII)
+		f.sb.WriteString("\n")
+		f.colored(cType, strings.Join(f.branches, "")+branch)
+		f.branches = append(f.branches, cont)
+		return
+	}
+	if !f.singleLine {
+		f.sb.WriteString(strings.Repeat(f.config.Indent, depth+1))
+	}
+}
+
+// endEntry writes the separator after entry i of n. Go syntax needs a
+// trailing comma after the last entry of a multi-line literal.
+func (f *formatter) endEntry(i, n int) {
+	if f.tree() {
+		return
+	}
+	if i < n-1 || (f.config.GoSyntax && !f.singleLine) {
+		f.sb.WriteString(",")
+	}
+	if !f.singleLine {
+		f.sb.WriteString("\n")
+	} else if i < n-1 {
+		f.sb.WriteString(" ")
+	}
+}
+
+// closeBlock writes the closing indentation and brace.
+func (f *formatter) closeBlock(brace string, depth int) {
+	if f.tree() {
+		return
+	}
+	if !f.singleLine {
+		f.sb.WriteString(strings.Repeat(f.config.Indent, depth))
+		if f.config.html {
+			f.sb.WriteString(blockEnd)
+		}
+	}
+	f.colored(cBrace, brace)
+}
+
 // tryInterfaces checks if the value implements PrettyPrinterConfig,
-// PrettyPrinter, fmt.Stringer, or error, in that order.
+// PrettyPrinter, slog.LogValuer, fmt.Stringer, or error, in that order.
+// With GoSyntax only the PrettyPrinter interfaces are used.
 // Returns true if an interface was used to format the value.
-func (f *formatter) tryInterfaces(v reflect.Value) bool {
+func (f *formatter) tryInterfaces(v reflect.Value, depth int) bool {
 	if !v.IsValid() || !v.CanInterface() {
 		return false
 	}
@@ -85,13 +231,13 @@ func (f *formatter) tryInterfaces(v reflect.Value) bool {
 
 	// 1. PrettyPrinterConfig (highest priority — format-aware)
 	if pp, ok := iface.(PrettyPrinterConfig); ok {
-		f.sb.WriteString(pp.PrettyPrintConfig(f.config))
+		f.writePrinted(pp.PrettyPrintConfig(f.config))
 		return true
 	}
 
 	// 2. PrettyPrinter
 	if pp, ok := iface.(PrettyPrinter); ok {
-		f.sb.WriteString(pp.PrettyPrint())
+		f.writePrinted(pp.PrettyPrint())
 		return true
 	}
 
@@ -99,29 +245,42 @@ func (f *formatter) tryInterfaces(v reflect.Value) bool {
 	if v.Kind() != reflect.Ptr && v.CanAddr() {
 		ptr := v.Addr().Interface()
 		if pp, ok := ptr.(PrettyPrinterConfig); ok {
-			f.sb.WriteString(pp.PrettyPrintConfig(f.config))
+			f.writePrinted(pp.PrettyPrintConfig(f.config))
 			return true
 		}
 		if pp, ok := ptr.(PrettyPrinter); ok {
-			f.sb.WriteString(pp.PrettyPrint())
+			f.writePrinted(pp.PrettyPrint())
 			return true
 		}
 	}
 
-	// 3. fmt.Stringer — only for non-struct types to avoid
+	if f.config.GoSyntax {
+		return false
+	}
+
+	// 3. slog.LogValuer and slog.Value — resolved like a slog handler would
+	if f.trySlog(iface, depth) {
+		return true
+	}
+
+	// 4. fmt.Stringer — only for non-struct types to avoid
 	//    losing struct detail (many structs implement Stringer
 	//    but you still want to see inside them by default)
 	if v.Kind() != reflect.Struct {
 		if s, ok := iface.(fmt.Stringer); ok {
-			f.colored(cString, fmt.Sprintf("%q", s.String()))
+			if text := s.String(); !f.maskText(text) {
+				f.colored(cString, fmt.Sprintf("%q", text))
+			}
 			return true
 		}
 	}
 
-	// 4. error interface
+	// 5. error interface
 	if v.Kind() != reflect.Struct {
 		if e, ok := iface.(error); ok {
-			f.colored(cNil, fmt.Sprintf("error(%q)", e.Error()))
+			if text := e.Error(); !f.maskText(text) {
+				f.colored(cNil, fmt.Sprintf("error(%q)", text))
+			}
 			return true
 		}
 	}
@@ -129,12 +288,26 @@ func (f *formatter) tryInterfaces(v reflect.Value) bool {
 	return false
 }
 
+// writePrinted writes the output of a PrettyPrint method as is, less
+// any block markers, which only the formatter may write.
+func (f *formatter) writePrinted(s string) {
+	if f.config.html {
+		s = blockMarks.Replace(s)
+	}
+	if !f.maskText(s) {
+		f.sb.WriteString(s)
+	}
+}
+
 func (f *formatter) formatStruct(v reflect.Value, depth int) {
 	t := v.Type()
-	indent := strings.Repeat(f.config.Indent, depth+1)
-	closingIndent := strings.Repeat(f.config.Indent, depth)
 
-	if f.config.ShowTypes {
+	switch {
+	case f.config.GoSyntax:
+		f.colored(cType, t.String())
+	case f.tree():
+		f.colored(cType, shortType(t))
+	case f.config.ShowTypes:
 		f.colored(cType, t.Name()+" ")
 	}
 
@@ -142,23 +315,20 @@ func (f *formatter) formatStruct(v reflect.Value, depth int) {
 	type fieldEntry struct {
 		displayName string
 		value       reflect.Value
+		field       structField
+		pos         int
 	}
 	var fields []fieldEntry
-
-	for i := 0; i < v.NumField(); i++ {
-		sf := t.Field(i)
-		if !sf.IsExported() {
+	for pos, sf := range f.config.structFields(t) {
+		fv, ok := sf.value(v)
+		if !ok || sf.omitted(fv) {
 			continue
 		}
-
-		name := resolveFieldName(sf, v.Field(i), f.config.UseJSONTags)
-		if name == "" {
-			continue
-		}
-
 		fields = append(fields, fieldEntry{
-			displayName: name,
-			value:       v.Field(i),
+			displayName: sf.name,
+			value:       fv,
+			field:       sf,
+			pos:         pos,
 		})
 	}
 
@@ -167,62 +337,37 @@ func (f *formatter) formatStruct(v reflect.Value, depth int) {
 		return
 	}
 
-	f.colored(cBrace, "{\n")
+	f.openBlock("{")
 
 	for i, fe := range fields {
-		f.sb.WriteString(indent)
+		f.entryIndent(depth, i, len(fields))
 		f.colored(cKey, fe.displayName)
 		f.sb.WriteString(": ")
-		f.format(fe.value, depth+1)
-		if i < len(fields)-1 {
-			f.sb.WriteString(",")
-		}
-		f.sb.WriteString("\n")
-	}
-
-	f.sb.WriteString(closingIndent)
-	f.colored(cBrace, "}")
-}
-
-// resolveFieldName returns the display name for a struct field.
-// Returns "" if the field should be skipped.
-func resolveFieldName(sf reflect.StructField, fieldValue reflect.Value, useJSONTags bool) string {
-	name := sf.Name
-	if !useJSONTags {
-		return name
-	}
-
-	tag := sf.Tag.Get("json")
-	if tag == "" {
-		return name
-	}
-
-	parts := strings.Split(tag, ",")
-	if parts[0] == "-" {
-		return ""
-	}
-	if parts[0] != "" {
-		name = parts[0]
+		f.formatChild(fe.value, depth+1, func() child {
+			return fieldChild(t, fe.pos, fe.field, v.FieldByIndex(fe.field.index), f.config)
+		})
+		f.endEntry(i, len(fields))
 	}
 
-	for _, opt := range parts[1:] {
-		if opt == "omitempty" && fieldValue.IsZero() {
-			return ""
-		}
-	}
-	return name
+	f.closeBlock("}", depth)
 }
 
 func (f *formatter) formatMap(v reflect.Value, depth int) {
 	if v.IsNil() {
-		f.colored(cNil, "nil")
+		if f.config.GoSyntax {
+			f.colored(cNil, v.Type().String()+"(nil)")
+		} else {
+			f.colored(cNil, "nil")
+		}
 		return
 	}
 
-	indent := strings.Repeat(f.config.Indent, depth+1)
-	closingIndent := strings.Repeat(f.config.Indent, depth)
-
-	if f.config.ShowTypes {
+	switch {
+	case f.config.GoSyntax:
+		f.colored(cType, v.Type().String())
+	case f.tree():
+		f.colored(cType, shortType(v.Type()))
+	case f.config.ShowTypes:
 		f.colored(cType, fmt.Sprintf("map[%s]%s ", v.Type().Key(), v.Type().Elem()))
 	}
 
@@ -235,63 +380,183 @@ func (f *formatter) formatMap(v reflect.Value, depth int) {
 	// Sort keys for deterministic output
 	sortedKeys := sortMapKeys(keys)
 
-	f.colored(cBrace, "{\n")
+	f.openBlock("{")
 	for i, key := range sortedKeys {
-		f.sb.WriteString(indent)
+		f.entryIndent(depth, i, len(sortedKeys))
+		inKey := f.inKey
+		f.inKey = true
 		f.format(key, depth+1)
+		f.inKey = inKey
 		f.sb.WriteString(": ")
-		f.format(v.MapIndex(key), depth+1)
-		if i < len(sortedKeys)-1 {
-			f.sb.WriteString(",")
-		}
-		f.sb.WriteString("\n")
+		f.formatChild(v.MapIndex(key), depth+1, func() child { return keyChild(key, v.MapIndex(key)) })
+		f.endEntry(i, len(sortedKeys))
 	}
-	f.sb.WriteString(closingIndent)
-	f.colored(cBrace, "}")
+	f.closeBlock("}", depth)
 }
 
 func (f *formatter) formatSlice(v reflect.Value, depth int) {
+	open, closing := "[", "]"
+	if f.config.GoSyntax {
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			f.colored(cNil, v.Type().String()+"(nil)")
+			return
+		}
+		f.colored(cType, v.Type().String())
+		open, closing = "{", "}"
+	}
+
 	if v.Kind() == reflect.Slice && v.IsNil() {
 		f.colored(cNil, "nil")
 		return
 	}
 
 	if v.Len() == 0 {
-		f.colored(cBrace, "[]")
+		f.colored(cBrace, open+closing)
 		return
 	}
 
 	// Compact for short simple slices
-	if v.Len() <= 5 && isSimpleKind(v.Type().Elem().Kind()) {
-		f.colored(cBrace, "[")
+	if isInlineSlice(v) {
+		f.colored(cBrace, open)
 		for i := 0; i < v.Len(); i++ {
 			if i > 0 {
 				f.sb.WriteString(", ")
 			}
-			f.format(v.Index(i), depth)
+			f.formatChild(v.Index(i), depth, func() child { return indexChild(i, v.Index(i)) })
 		}
-		f.colored(cBrace, "]")
+		f.colored(cBrace, closing)
 		return
 	}
 
-	indent := strings.Repeat(f.config.Indent, depth+1)
-	closingIndent := strings.Repeat(f.config.Indent, depth)
-
-	f.colored(cBrace, "[\n")
+	if f.tree() {
+		f.colored(cType, shortType(v.Type()))
+	}
+	f.openBlock(open)
 	for i := 0; i < v.Len(); i++ {
-		f.sb.WriteString(indent)
-		f.format(v.Index(i), depth+1)
-		if i < v.Len()-1 {
-			f.sb.WriteString(",")
+		f.entryIndent(depth, i, v.Len())
+		f.formatChild(v.Index(i), depth+1, func() child { return indexChild(i, v.Index(i)) })
+		f.endEntry(i, v.Len())
+	}
+	f.closeBlock(closing, depth)
+}
+
+// formatSummary writes a one-line summary of a struct, map, slice or
+// array in place of its entries: User{…5 fields}, []Order(len=37),
+// map[string]int(len=4). amp marks a value reached through a pointer.
+// Returns false for other values and for nil or empty ones, which are
+// formatted as usual.
+func (f *formatter) formatSummary(v reflect.Value, amp bool) bool {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+		if v.IsNil() || hasPrinter(v) {
+			return false
 		}
-		f.sb.WriteString("\n")
+		if v.Kind() == reflect.Ptr {
+			amp = true
+		}
+		v = v.Elem()
+	}
+	if !isCompositeKind(v.Kind()) || hasPrinter(v) {
+		return false
+	}
+	var count int
+	switch v.Kind() {
+	case reflect.Struct:
+		count = visibleFields(v, f.config)
+	case reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return false
+		}
+		count = v.Len()
+	default:
+		count = v.Len()
+	}
+	if count == 0 {
+		return false
+	}
+
+	typ := shortType(v.Type())
+	if amp {
+		typ = "&" + typ
 	}
-	f.sb.WriteString(closingIndent)
-	f.colored(cBrace, "]")
+	if v.Kind() == reflect.Struct {
+		f.colored(cType, typ)
+		f.colored(cBrace, "{")
+		if count == 1 {
+			f.colored(cType, "…1 field")
+		} else {
+			f.colored(cType, fmt.Sprintf("…%d fields", count))
+		}
+		f.colored(cBrace, "}")
+	} else {
+		f.colored(cType, fmt.Sprintf("%s(len=%d)", typ, count))
+	}
+	return true
 }
 
 // --- Helpers ---
 
+// tree reports whether values are drawn as a tree (Config.Tree).
+func (f *formatter) tree() bool {
+	return f.config.Tree && !f.config.GoSyntax && !f.singleLine
+}
+
+// treeBranch returns the branch drawn before an entry and the
+// continuation drawn below it for the entry's own entries.
+func treeBranch(last, ascii bool) (branch, cont string) {
+	switch {
+	case last && ascii:
+		return "`-- ", "    "
+	case last:
+		return "└── ", "    "
+	case ascii:
+		return "|-- ", "|   "
+	}
+	return "├── ", "│   "
+}
+
+// isInlineSlice reports whether v is a slice or array short and simple
+// enough to print on one line.
+func isInlineSlice(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Len() <= 5 && isSimpleKind(v.Type().Elem().Kind())
+	}
+	return false
+}
+
+// visibleFields counts the fields of struct v that are printed.
+func visibleFields(v reflect.Value, c Config) int {
+	n := 0
+	for _, sf := range c.structFields(v.Type()) {
+		if fv, ok := sf.value(v); ok && !sf.omitted(fv) {
+			n++
+		}
+	}
+	return n
+}
+
+// shortType returns the name of t without package qualifiers, e.g.
+// []Order for []shop.Order.
+func shortType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + shortType(t.Elem())
+	case reflect.Slice:
+		return "[]" + shortType(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), shortType(t.Elem()))
+	case reflect.Map:
+		return "map[" + shortType(t.Key()) + "]" + shortType(t.Elem())
+	}
+	if t.Name() != "" {
+		return t.Name()
+	}
+	if t.Kind() == reflect.Struct {
+		return "struct"
+	}
+	return t.String()
+}
+
 func isSimpleKind(k reflect.Kind) bool {
 	switch k {
 	case reflect.Bool,
@@ -304,6 +569,14 @@ func isSimpleKind(k reflect.Kind) bool {
 	return false
 }
 
+func isCompositeKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
 func formatFloat(f float64) string {
 	// Use comma-friendly format: show decimals only if needed
 	if f == float64(int64(f)) {
@@ -0,0 +1,201 @@
+package pf
+
+import (
+	"html"
+	"strings"
+)
+
+// HTMLStyle is a stylesheet for the output of SprintHTML and
+// SprintDiffHTML. Include it in a <style> element, or style the pf-*
+// classes yourself.
+const HTMLStyle = `.pf { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; }
+.pf div, .pf summary { white-space: pre; }
+.pf summary { display: block; cursor: pointer; list-style: none; }
+.pf summary::-webkit-details-marker { display: none; }
+.pf details:not([open]) > summary::after { content: " …"; color: #6e7781; }
+.pf-key { color: #0550ae; }
+.pf-string { color: #0a3069; }
+.pf-number { color: #953800; }
+.pf-bool { color: #8250df; }
+.pf-nil { color: #cf222e; }
+.pf-type { color: #6e7781; }
+.pf-brace { color: #57606a; }
+.pf-del { color: #82071e; }
+.pf-add { color: #116329; }
+.pf-del-hi { background: #ffcecb; }
+.pf-add-hi { background: #aceebb; }
+.pf-line-del { background: #ffebe9; }
+.pf-line-add { background: #dafbe1; }
+`
+
+// Markers written around blocks when Config.html is set. Strings are
+// printed quoted, and the markers are removed from PrettyPrint output and
+// from the lines of multi-line string diffs, so they cannot come from a
+// value.
+const (
+	blockStart = "\x01"
+	blockEnd   = "\x02"
+)
+
+// Classes of the colors in printed values and in diffs. Deletions and
+// nil values share a color, as do additions and strings.
+var (
+	valueClasses = map[string]string{
+		cKey:    "pf-key",
+		cString: "pf-string",
+		cNumber: "pf-number",
+		cBool:   "pf-bool",
+		cType:   "pf-type",
+		cNil:    "pf-nil",
+		cBrace:  "pf-brace",
+	}
+	diffClasses = map[string]string{
+		cKey:       "pf-key",
+		cType:      "pf-type",
+		cBrace:     "pf-brace",
+		cDiffDel:   "pf-del",
+		cDiffAdd:   "pf-add",
+		cDiffDelHi: "pf-del-hi",
+		cDiffAddHi: "pf-add-hi",
+	}
+)
+
+// SprintHTML returns v pretty-printed as an HTML fragment: a <div
+// class="pf"> with one <div> per line and a <span> with a pf-* class
+// per colored token. Nested structs, maps and slices are collapsible
+// <details> elements. See HTMLStyle for the classes.
+func (c Config) SprintHTML(v interface{}) string {
+	c.ColorMode, c.Theme = true, Theme{}
+	c.html = true
+	return renderHTML(c.Sprint(v), valueClasses, false)
+}
+
+// SprintDiffHTML returns the diff between a and b as an HTML fragment
+// like SprintHTML's. Lines removed or added also get the pf-line-del or
+// pf-line-add class.
+func (c Config) SprintDiffHTML(a, b interface{}) string {
+	c.ColorMode, c.Theme = true, Theme{}
+	c.html = true
+	return renderHTML(c.SprintDiff(a, b), diffClasses, true)
+}
+
+var blockMarks = strings.NewReplacer(blockStart, "", blockEnd, "")
+
+// segment is a run of output text in one color.
+type segment struct {
+	color, text string
+}
+
+// splitColored splits output written with ColorMode into lines of
+// colored segments. A color set on one line carries over to the next
+// until it is reset.
+func splitColored(s string) [][]segment {
+	var lines [][]segment
+	var line []segment
+	color := ""
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, "\033["):
+			end := strings.IndexByte(s, 'm')
+			if end < 0 {
+				end = len(s) - 1
+			}
+			if code := s[:end+1]; code == cReset {
+				color = ""
+			} else {
+				color = code
+			}
+			s = s[end+1:]
+		case s[0] == '\n':
+			lines = append(lines, line)
+			line = nil
+			s = s[1:]
+		default:
+			n := strings.IndexAny(s, "\033\n")
+			if n < 0 {
+				n = len(s)
+			}
+			line = append(line, segment{color, s[:n]})
+			s = s[n:]
+		}
+	}
+	return append(lines, line)
+}
+
+// lineKind returns '-' or '+' if a diff line is part of a removed or
+// added entry, judged by the color of its first colored text, and ' '
+// otherwise.
+func lineKind(line []segment) byte {
+	for _, sg := range line {
+		if sg.color == "" || strings.TrimSpace(sg.text) == "" {
+			continue
+		}
+		switch sg.color {
+		case cDiffDel, cDiffDelHi:
+			return '-'
+		case cDiffAdd, cDiffAddHi:
+			return '+'
+		}
+		return ' '
+	}
+	return ' '
+}
+
+// renderHTML converts colored output to HTML, turning the lines between
+// block markers into <details> elements.
+func renderHTML(out string, classes map[string]string, diff bool) string {
+	var sb strings.Builder
+	sb.WriteString(`<div class="pf">`)
+	sb.WriteString("\n")
+	open := 0
+	for _, line := range splitColored(out) {
+		var text strings.Builder
+		starts, ends := false, false
+		for _, sg := range line {
+			if strings.Contains(sg.text, blockStart) {
+				starts = true
+			}
+			if strings.Contains(sg.text, blockEnd) {
+				ends = true
+			}
+			t := blockMarks.Replace(sg.text)
+			if t == "" {
+				continue
+			}
+			t = html.EscapeString(t)
+			if class := classes[sg.color]; class != "" {
+				text.WriteString(`<span class="` + class + `">` + t + `</span>`)
+			} else {
+				text.WriteString(t)
+			}
+		}
+
+		if text.Len() == 0 {
+			text.WriteString("<br>")
+		}
+		attr := ""
+		if diff {
+			switch lineKind(line) {
+			case '-':
+				attr = ` class="pf-line-del"`
+			case '+':
+				attr = ` class="pf-line-add"`
+			}
+		}
+		switch {
+		case starts:
+			// The line opening a block is the summary of its <details>.
+			open++
+			sb.WriteString("<details open><summary" + attr + ">" + text.String() + "</summary>\n")
+		case ends && open > 0:
+			open--
+			sb.WriteString("<div" + attr + ">" + text.String() + "</div></details>\n")
+		default:
+			sb.WriteString("<div" + attr + ">" + text.String() + "</div>\n")
+		}
+	}
+	// Eliding unchanged lines of a diff can drop the end of a block.
+	sb.WriteString(strings.Repeat("</details>", open))
+	sb.WriteString("</div>")
+	return sb.String()
+}
@@ -0,0 +1,9 @@
+// Package term queries and configures the terminal for pf and pftest.
+package term
+
+// IsTerminal reports whether fd is a terminal, by asking for its size.
+// Other character devices such as /dev/null have none.
+func IsTerminal(fd uintptr) bool {
+	_, _, ok := Size(fd)
+	return ok
+}
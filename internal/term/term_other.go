@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package term
+
+import "errors"
+
+// Size is not supported on this platform.
+func Size(fd uintptr) (cols, rows int, ok bool) {
+	return 0, 0, false
+}
+
+// MakeRaw is not supported on this platform.
+func MakeRaw(fd uintptr) (restore func(), err error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
@@ -0,0 +1,26 @@
+package term
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if IsTerminal(f.Fd()) {
+		t.Error("expected regular file not to be a terminal")
+	}
+
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer null.Close()
+	if IsTerminal(null.Fd()) {
+		t.Errorf("expected %s not to be a terminal", os.DevNull)
+	}
+}
@@ -0,0 +1,172 @@
+package pf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchOp is a single RFC 6902 JSON Patch operation.
+type JSONPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+	// From is the source pointer of "move" and "copy" operations.
+	From string `json:"from,omitempty"`
+}
+
+// JSONPatchOps returns the RFC 6902 operations that turn a into b.
+//
+// Both values are first encoded with encoding/json, so field names,
+// omitted fields and custom marshalers follow the json tags and
+// MarshalJSON methods exactly. Arrays are compared index by index.
+func JSONPatchOps(a, b interface{}) ([]JSONPatchOp, error) {
+	ga, err := toJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+	gb, err := toJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := Config{}.Changes(ga, gb)
+	ops := make([]JSONPatchOp, 0, len(changes))
+	for i := 0; i < len(changes); i++ {
+		ch := changes[i]
+		if ch.Kind == Removed && isIndexPath(ch.Path) {
+			// Removing array elements shifts later indices, so a run
+			// of removals from the same array is emitted last-first.
+			j := i
+			for j+1 < len(changes) && changes[j+1].Kind == Removed &&
+				isIndexPath(changes[j+1].Path) && sameParent(changes[j+1].Path, ch.Path) {
+				j++
+			}
+			for k := j; k >= i; k-- {
+				ops = append(ops, JSONPatchOp{Op: "remove", Path: jsonPointer(changes[k].Path)})
+			}
+			i = j
+			continue
+		}
+
+		op := JSONPatchOp{Path: jsonPointer(ch.Path)}
+		switch ch.Kind {
+		case Added:
+			op.Op = "add"
+		case Removed:
+			op.Op = "remove"
+		default:
+			op.Op = "replace"
+		}
+		if ch.Kind != Removed {
+			if op.Value, err = json.Marshal(ch.New); err != nil {
+				return nil, err
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// JSONPatch returns an RFC 6902 JSON Patch document that turns a into b.
+func JSONPatch(a, b interface{}) ([]byte, error) {
+	ops, err := JSONPatchOps(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ops)
+}
+
+// MergePatch returns an RFC 7386 JSON Merge Patch document that turns
+// a into b. Removed object members are set to null, and changed arrays
+// are replaced as a whole. Note that merge patches cannot set a member
+// to null, since null means removal.
+func MergePatch(a, b interface{}) ([]byte, error) {
+	ga, err := toJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+	gb, err := toJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(ga, gb))
+}
+
+func mergePatch(a, b interface{}) interface{} {
+	ma, okA := a.(map[string]interface{})
+	mb, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		return b
+	}
+	patch := make(map[string]interface{})
+	for k := range ma {
+		if _, ok := mb[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, vb := range mb {
+		va, ok := ma[k]
+		if !ok {
+			patch[k] = vb
+			continue
+		}
+		if reflect.DeepEqual(va, vb) {
+			continue
+		}
+		if _, isObj := vb.(map[string]interface{}); isObj {
+			patch[k] = mergePatch(va, vb)
+		} else {
+			patch[k] = vb
+		}
+	}
+	return patch
+}
+
+// toJSONValue converts v to its generic encoding/json representation
+// (maps, slices, json.Number, string, bool and nil).
+func toJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("pf: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var g interface{}
+	if err := dec.Decode(&g); err != nil {
+		return nil, fmt.Errorf("pf: %w", err)
+	}
+	return g, nil
+}
+
+// jsonPointer renders p as an RFC 6901 JSON Pointer.
+func jsonPointer(p Path) string {
+	var sb strings.Builder
+	for _, e := range p {
+		sb.WriteString("/")
+		switch e.Kind {
+		case FieldElem:
+			sb.WriteString(escapePointer(e.Name))
+		case KeyElem:
+			sb.WriteString(escapePointer(fmt.Sprint(e.Key)))
+		case IndexElem:
+			sb.WriteString(strconv.Itoa(e.Index))
+		}
+	}
+	return sb.String()
+}
+
+func escapePointer(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+func isIndexPath(p Path) bool {
+	return len(p) > 0 && p[len(p)-1].Kind == IndexElem
+}
+
+func sameParent(a, b Path) bool {
+	return len(a) == len(b) && len(a) > 0 && comparePaths(a[:len(a)-1], b[:len(b)-1]) == 0
+}
@@ -0,0 +1,74 @@
+package pf
+
+import "strings"
+
+// SprintMarkdown returns v pretty-printed in a fenced code block, for
+// GitHub-flavored Markdown.
+func (c Config) SprintMarkdown(v interface{}) string {
+	c.ColorMode = false
+	return fence("", c.Sprint(v))
+}
+
+// SprintDiffMarkdown returns the diff between a and b in a fenced diff
+// block. The - or + of removed and added lines is moved to the first
+// column, where GitHub highlights it.
+func (c Config) SprintDiffMarkdown(a, b interface{}) string {
+	// Colors tell removed and added lines, and their continuations,
+	// from unchanged ones.
+	c.ColorMode, c.Theme = true, Theme{}
+	var sb strings.Builder
+	for i, line := range splitColored(c.SprintDiff(a, b)) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		kind := lineKind(line)
+		var text strings.Builder
+		marked, unmarked := false, false
+		for _, sg := range line {
+			t := sg.text
+			if !marked && sg.color != "" && strings.TrimSpace(t) != "" {
+				marked = true
+				switch {
+				case strings.HasPrefix(t, "--- ") || strings.HasPrefix(t, "+++ "):
+					// A label header is already in diff syntax.
+					kind = 0
+				case kind != ' ' && (strings.HasPrefix(t, "- ") || strings.HasPrefix(t, "+ ")):
+					t = t[2:]
+				default:
+					unmarked = true
+				}
+			}
+			text.WriteString(t)
+		}
+		out := text.String()
+		if kind != ' ' && unmarked {
+			out = unindentContinuation(out)
+		}
+		if kind != 0 {
+			sb.WriteByte(kind)
+		}
+		sb.WriteString(out)
+	}
+	return fence("diff", sb.String())
+}
+
+// unindentContinuation removes the two spaces that align a following
+// line of a removed or added value with the text after its marker.
+func unindentContinuation(line string) string {
+	i := len(line) - len(strings.TrimLeft(line, " │|"))
+	if i >= 2 && line[i-2:i] == "  " {
+		return line[:i-2] + line[i:]
+	}
+	return line
+}
+
+// fence wraps s in a fenced code block of the given language, with a
+// fence longer than any run of backticks in s.
+func fence(lang, s string) string {
+	n := 3
+	for strings.Contains(s, strings.Repeat("`", n)) {
+		n++
+	}
+	f := strings.Repeat("`", n)
+	return f + lang + "\n" + s + "\n" + f
+}
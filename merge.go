@@ -0,0 +1,164 @@
+package pf
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// MergeSource reports which side of a three-way comparison changed a path.
+type MergeSource int
+
+const (
+	// FromOurs means only ours changed the path.
+	FromOurs MergeSource = iota + 1
+	// FromTheirs means only theirs changed the path.
+	FromTheirs
+	// FromBoth means ours and theirs made the identical change.
+	FromBoth
+	// Conflict means ours and theirs changed the path differently.
+	Conflict
+)
+
+func (s MergeSource) String() string {
+	switch s {
+	case FromOurs:
+		return "ours"
+	case FromTheirs:
+		return "theirs"
+	case FromBoth:
+		return "both"
+	case Conflict:
+		return "conflict"
+	}
+	return "MergeSource(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Change3 is a single difference in a three-way comparison.
+type Change3 struct {
+	Path   Path
+	Source MergeSource
+	// Ours and Theirs are the changes each side made relative to base,
+	// or nil if that side left the path untouched.
+	Ours   *Change
+	Theirs *Change
+}
+
+// Diff3 compares ours and theirs against their common base using this
+// config and reports, per changed path, which side the change came from.
+//
+// A path is a Conflict when both sides changed it differently, when
+// one side changed a value that contains a path changed by the other,
+// or when theirs changed a slice element that ours moved by adding or
+// removing an element before it.
+func (c Config) Diff3(base, ours, theirs interface{}) []Change3 {
+	oc := c.Changes(base, ours)
+	tc := c.Changes(base, theirs)
+
+	var result []Change3
+	i, j := 0, 0
+	for i < len(oc) || j < len(tc) {
+		var cmp int
+		switch {
+		case i >= len(oc):
+			cmp = 1
+		case j >= len(tc):
+			cmp = -1
+		default:
+			cmp = comparePaths(oc[i].Path, tc[j].Path)
+		}
+
+		switch {
+		case cmp < 0:
+			result = append(result, Change3{Path: oc[i].Path, Source: FromOurs, Ours: &oc[i]})
+			i++
+		case cmp > 0:
+			result = append(result, Change3{Path: tc[j].Path, Source: FromTheirs, Theirs: &tc[j]})
+			j++
+		default:
+			src := Conflict
+			if c.sameChange(oc[i], tc[j]) {
+				src = FromBoth
+			}
+			result = append(result, Change3{Path: oc[i].Path, Source: src, Ours: &oc[i], Theirs: &tc[j]})
+			i++
+			j++
+		}
+	}
+
+	// A change to a parent value overlaps every change beneath it.
+	for i := range result {
+		for j := range result {
+			if i == j || result[i].Source == FromBoth || result[j].Source == FromBoth {
+				continue
+			}
+			if result[i].Ours != nil && result[j].Theirs != nil &&
+				(hasPathPrefix(result[j].Path, result[i].Path) || hasPathPrefix(result[i].Path, result[j].Path)) {
+				result[i].Source = Conflict
+				result[j].Source = Conflict
+			}
+		}
+	}
+
+	// Theirs changes slice elements by their index in base, which is
+	// another element in ours once ours has added or removed one before.
+	for i := range result {
+		for j := range result {
+			if result[i].Source == FromTheirs && result[j].Source == FromOurs && shifts(*result[j].Ours, result[i].Path) {
+				result[i].Source = Conflict
+				result[j].Source = Conflict
+			}
+		}
+	}
+	return result
+}
+
+// shifts reports whether ch adds or removes a slice element at or
+// before the element path is in, moving it to another index.
+func shifts(ch Change, path Path) bool {
+	n := len(ch.Path)
+	if (ch.Kind != Added && ch.Kind != Removed) || n == 0 || ch.Path[n-1].Kind != IndexElem {
+		return false
+	}
+	parent := ch.Path[:n-1]
+	return len(path) > len(parent) && hasPathPrefix(path, parent) &&
+		path[n-1].Kind == IndexElem && path[n-1].Index >= ch.Path[n-1].Index
+}
+
+func (c Config) sameChange(a, b Change) bool {
+	if a.Kind != b.Kind {
+		return false
+	}
+	w := &changeWalker{config: c}
+	return w.render(reflect.ValueOf(a.New)) == w.render(reflect.ValueOf(b.New))
+}
+
+// Merge3 merges the changes ours and theirs made to base using this
+// config. The result starts as a deep copy of ours, and every change
+// made only by theirs is applied to it. Conflicting paths keep ours and
+// are returned as conflicts. The merged value has the same type as ours.
+func (c Config) Merge3(base, ours, theirs interface{}) (interface{}, []Change3, error) {
+	if ours == nil {
+		return nil, nil, fmt.Errorf("pf: merge: ours is nil")
+	}
+	changes := c.Diff3(base, ours, theirs)
+
+	merged := reflect.New(reflect.TypeOf(ours)).Elem()
+	merged.Set(deepCopy(reflect.ValueOf(ours)))
+
+	var apply []Change
+	var conflicts []Change3
+	for _, ch := range changes {
+		switch ch.Source {
+		case FromTheirs:
+			apply = append(apply, *ch.Theirs)
+		case Conflict:
+			conflicts = append(conflicts, ch)
+		}
+	}
+
+	if err := applyChanges(merged, apply); err != nil {
+		return nil, conflicts, fmt.Errorf("pf: merge: %w", err)
+	}
+	return merged.Interface(), conflicts, nil
+}
@@ -0,0 +1,94 @@
+package pf
+
+// Option changes a setting of a Config. The package-level functions
+// take options that apply to one call only:
+//
+//	pf.Print(v, pf.WithDepth(3))
+//	pf.Diff(a, b, pf.WithLabels("want", "got"))
+//
+// An Option is a plain function, so settings without their own helper
+// can be set inline:
+//
+//	pf.Print(v, func(c *pf.Config) { c.DiffSummary = true })
+type Option func(*Config)
+
+// With returns a copy of c with opts applied.
+func (c Config) With(opts ...Option) Config {
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// WithIndent sets the indent string per level (Config.Indent).
+func WithIndent(indent string) Option {
+	return func(c *Config) { c.Indent = indent }
+}
+
+// WithTypes sets whether type names are shown (Config.ShowTypes).
+func WithTypes(show bool) Option {
+	return func(c *Config) { c.ShowTypes = show }
+}
+
+// WithJSONTags sets whether json tag names replace field names
+// (Config.UseJSONTags).
+func WithJSONTags(use bool) Option {
+	return func(c *Config) { c.UseJSONTags = use }
+}
+
+// WithEmbedded sets how the fields of embedded structs are printed
+// (Config.Embedded).
+func WithEmbedded(mode EmbedMode) Option {
+	return func(c *Config) { c.Embedded = mode }
+}
+
+// WithDepth limits the nesting depth (Config.MaxDepth, 0 = unlimited).
+func WithDepth(depth int) Option {
+	return func(c *Config) { c.MaxDepth = depth }
+}
+
+// WithColor sets whether output is colored (Config.ColorMode).
+func WithColor(color bool) Option {
+	return func(c *Config) { c.ColorMode = color }
+}
+
+// WithWidth sets the maximum line width (Config.Width).
+func WithWidth(width int) Option {
+	return func(c *Config) { c.Width = width }
+}
+
+// WithGoSyntax sets whether values are rendered as Go composite
+// literals (Config.GoSyntax).
+func WithGoSyntax(goSyntax bool) Option {
+	return func(c *Config) { c.GoSyntax = goSyntax }
+}
+
+// WithTree sets whether nested values are drawn as a tree (Config.Tree).
+func WithTree(tree bool) Option {
+	return func(c *Config) { c.Tree = tree }
+}
+
+// WithMaskSecrets sets whether values that look like secrets are
+// masked (Config.MaskSecrets).
+func WithMaskSecrets(mask bool) Option {
+	return func(c *Config) { c.MaskSecrets = mask }
+}
+
+// WithRules adds rules after those already set (Config.Rules).
+func WithRules(rules ...Rule) Option {
+	return func(c *Config) {
+		c.Rules = append(append([]Rule(nil), c.Rules...), rules...)
+	}
+}
+
+// WithLabels sets the diff header labels (Config.OldLabel and
+// Config.NewLabel).
+func WithLabels(oldLabel, newLabel string) Option {
+	return func(c *Config) { c.OldLabel, c.NewLabel = oldLabel, newLabel }
+}
+
+// WithDiffContext limits diffs to n unchanged lines around each change
+// (Config.DiffContext).
+func WithDiffContext(n int) Option {
+	return func(c *Config) { c.DiffContext = n }
+}
@@ -0,0 +1,636 @@
+package pf
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrTruncated is returned when parsing output summarized by
+// Config.MaxDepth or a Rule, such as User{…5 fields} or []int(len=4).
+var ErrTruncated = errors.New("value truncated")
+
+// ParseError reports invalid pf output.
+type ParseError struct {
+	// Line and Column locate the error, starting at 1.
+	Line, Column int
+	Msg          string
+	// Err is the underlying error, if any (e.g. ErrTruncated).
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("pf: parse error at line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Parse reads pf output back into a generic value. Structs and maps
+// become map[string]interface{} (non-string map keys are kept in their
+// printed form), slices and arrays become []interface{}, integers
+// int64 (uint64 if too large), floats float64, strings, bools and nil.
+// Stringer and error values come back as their string.
+//
+// Parse accepts the output of the default config, ShowTypes, GoSyntax
+// and Width, with or without colors. Output of custom PrettyPrinter
+// implementations can only be parsed if it uses the same syntax.
+//
+//	v, err := pf.Parse(`{Name: "John", Tags: ["a"]}`)
+func Parse(s string) (interface{}, error) {
+	p := &parser{s: stripANSI(s)}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.i < len(p.s) {
+		return nil, p.errorf("unexpected %q after value", p.rest())
+	}
+	return v, nil
+}
+
+// Unmarshal parses pf output into the value pointed to by v. Struct
+// fields are matched by Go name or json tag name, like Config.UseJSONTags
+// output, falling back to a case-insensitive match. Fields missing
+// from the input keep their value and unknown names are ignored.
+// Mismatched values are reported as a *PathError wrapping
+// ErrTypeMismatch.
+//
+// Values printed by a String or Error method are read back with
+// UnmarshalText if the type has one, and time.Duration with
+// time.ParseDuration. Other such values, e.g. an int type with a String
+// method, do not round-trip and fail with ErrTypeMismatch.
+//
+//	var u User
+//	err := pf.Unmarshal(dumpFromLog, &u)
+func Unmarshal(s string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("pf: unmarshal: need a non-nil pointer, got %T", v)
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	return unmarshalValue(rv.Elem(), parsed, nil)
+}
+
+type parser struct {
+	s string
+	i int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) *ParseError {
+	line := 1 + strings.Count(p.s[:p.i], "\n")
+	col := 1 + utf8.RuneCountInString(p.s[strings.LastIndexByte(p.s[:p.i], '\n')+1:p.i])
+	return &ParseError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// rest returns a short excerpt of the remaining input for errors.
+func (p *parser) rest() string {
+	r := p.s[p.i:]
+	if i := strings.IndexByte(r, '\n'); i >= 0 {
+		r = r[:i]
+	}
+	if len(r) > 20 {
+		r = r[:20] + "…"
+	}
+	return r
+}
+
+func (p *parser) truncated() *ParseError {
+	err := p.errorf("value truncated")
+	err.Err = ErrTruncated
+	return err
+}
+
+func (p *parser) skipSpace() {
+	for p.i < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.i]) >= 0 {
+		p.i++
+	}
+}
+
+func (p *parser) peek() byte {
+	if p.i < len(p.s) {
+		return p.s[p.i]
+	}
+	return 0
+}
+
+func (p *parser) value() (interface{}, error) {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case c == 0:
+		return nil, p.errorf("unexpected end of input")
+	case c == '"' || c == '`':
+		return p.quoted()
+	case c == '{':
+		return p.block('}', false)
+	case c == '[':
+		if p.atTypePrefix() {
+			return p.typed()
+		}
+		return p.block(']', true)
+	case c == '&':
+		p.i++
+		return p.value()
+	case c == '(':
+		// (*T)(nil), (chan T)(0xc000…), (chan T), (func …)
+		if err := p.skipParens(); err != nil {
+			return nil, err
+		}
+		if p.peek() == '(' {
+			if err := p.skipParens(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case c == '-' || c == '+' || c >= '0' && c <= '9':
+		return p.number()
+	case c == '_' || c == '*' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return p.word()
+	}
+	return nil, p.errorf("unexpected %q", p.rest())
+}
+
+func (p *parser) quoted() (interface{}, error) {
+	q := p.s[p.i]
+	for j := p.i + 1; j < len(p.s); j++ {
+		switch {
+		case q == '"' && p.s[j] == '\\':
+			j++
+		case q == '"' && p.s[j] == '\n':
+			return nil, p.errorf("newline in string")
+		case p.s[j] == q:
+			s, err := strconv.Unquote(p.s[p.i : j+1])
+			if err != nil {
+				return nil, p.errorf("invalid string %s", p.s[p.i:j+1])
+			}
+			p.i = j + 1
+			return s, nil
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+func (p *parser) number() (interface{}, error) {
+	start := p.i
+	switch {
+	case strings.HasPrefix(p.s[p.i:], "+Inf"):
+		p.i += len("+Inf")
+		return math.Inf(1), nil
+	case strings.HasPrefix(p.s[p.i:], "-Inf"):
+		p.i += len("-Inf")
+		return math.Inf(-1), nil
+	}
+	if p.peek() == '-' || p.peek() == '+' {
+		p.i++
+	}
+	isFloat := false
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' || c == 'e' || c == 'E':
+			isFloat = true
+		case (c == '-' || c == '+') && (p.s[p.i-1] == 'e' || p.s[p.i-1] == 'E'):
+		default:
+			goto done
+		}
+		p.i++
+	}
+done:
+	lit := p.s[start:p.i]
+	if !isFloat {
+		if n, err := strconv.ParseInt(lit, 10, 64); err == nil {
+			return n, nil
+		}
+		if n, err := strconv.ParseUint(lit, 10, 64); err == nil {
+			return n, nil
+		}
+	}
+	f, err := strconv.ParseFloat(lit, 64)
+	if err != nil {
+		p.i = start
+		return nil, p.errorf("invalid number %q", lit)
+	}
+	return f, nil
+}
+
+// word parses a value starting with an identifier: nil, true, false,
+// NaN, error("…"), or a typed value such as User {…}, pf.User{…},
+// map[string]int{…} or pf.Status(1).
+func (p *parser) word() (interface{}, error) {
+	start := p.i
+	ident := p.ident()
+	switch ident {
+	case "nil":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "NaN":
+		return math.NaN(), nil
+	case "error":
+		if p.peek() == '(' {
+			p.i++
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			if p.skipSpace(); p.peek() != ')' {
+				return nil, p.errorf("expected ) after error message")
+			}
+			p.i++
+			return v, nil
+		}
+	}
+	p.i = start
+	return p.typed()
+}
+
+// typed parses a type name followed by a composite literal, a
+// conversion T(v), or T(nil).
+func (p *parser) typed() (interface{}, error) {
+	start := p.i
+	list := p.peek() == '['
+	if err := p.skipType(); err != nil {
+		return nil, err
+	}
+	typ := p.s[start:p.i]
+	p.skipSpace()
+	switch p.peek() {
+	case '{':
+		return p.block('}', list)
+	case '(':
+		if strings.HasPrefix(p.s[p.i:], "(len=") {
+			return nil, p.truncated()
+		}
+		p.i++
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace(); p.peek() != ')' {
+			return nil, p.errorf("expected ) after %s conversion", typ)
+		}
+		p.i++
+		return v, nil
+	}
+	p.i = start
+	return nil, p.errorf("unexpected %q", p.rest())
+}
+
+// atTypePrefix reports whether a "[" starts a slice or array type such
+// as []string or [2]int rather than a list.
+func (p *parser) atTypePrefix() bool {
+	j := p.i + 1
+	for j < len(p.s) && p.s[j] >= '0' && p.s[j] <= '9' {
+		j++
+	}
+	if j >= len(p.s)-1 || p.s[j] != ']' {
+		return false
+	}
+	c := p.s[j+1]
+	return c == '*' || c == '[' || c == '_' || unicode.IsLetter(rune(c))
+}
+
+func (p *parser) ident() string {
+	start := p.i
+	for p.i < len(p.s) {
+		r, size := utf8.DecodeRuneInString(p.s[p.i:])
+		if r != '_' && r != '.' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		p.i += size
+	}
+	return p.s[start:p.i]
+}
+
+// skipType skips a Go type expression as printed by reflect.Type.String.
+func (p *parser) skipType() error {
+	switch {
+	case p.peek() == '*':
+		p.i++
+		return p.skipType()
+	case p.peek() == '[':
+		if err := p.skipDelimited('[', ']'); err != nil {
+			return err
+		}
+		return p.skipType()
+	case strings.HasPrefix(p.s[p.i:], "map["):
+		p.i += len("map")
+		if err := p.skipDelimited('[', ']'); err != nil {
+			return err
+		}
+		return p.skipType()
+	case strings.HasPrefix(p.s[p.i:], "chan "):
+		p.i += len("chan ")
+		return p.skipType()
+	case strings.HasPrefix(p.s[p.i:], "interface {"), strings.HasPrefix(p.s[p.i:], "struct {"):
+		p.ident()
+		p.skipSpace()
+		return p.skipDelimited('{', '}')
+	case strings.HasPrefix(p.s[p.i:], "func("):
+		p.ident()
+		return p.skipDelimited('(', ')')
+	}
+	if p.ident() == "" {
+		return p.errorf("unexpected %q", p.rest())
+	}
+	if p.peek() == '[' { // generic type arguments
+		return p.skipDelimited('[', ']')
+	}
+	return nil
+}
+
+func (p *parser) skipParens() error {
+	return p.skipDelimited('(', ')')
+}
+
+// skipDelimited skips a balanced open…close group.
+func (p *parser) skipDelimited(open, close byte) error {
+	start := p.i
+	depth := 0
+	for ; p.i < len(p.s); p.i++ {
+		switch p.s[p.i] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				p.i++
+				return nil
+			}
+		}
+	}
+	p.i = start
+	return p.errorf("missing %q", close)
+}
+
+// block parses the entries of a {…} or […] literal. Entries are
+// "key: value" pairs, making an object, or bare values, making a list.
+// list forces a list, e.g. for the Go syntax of slices: []int{1, 2}.
+func (p *parser) block(close byte, list bool) (interface{}, error) {
+	p.i++ // opening brace
+	var elems []interface{}
+	var obj map[string]interface{}
+	for {
+		p.skipSpace()
+		if p.peek() == close {
+			p.i++
+			break
+		}
+		if strings.HasPrefix(p.s[p.i:], "…") {
+			return nil, p.truncated()
+		}
+
+		key, isKey, err := p.key(list)
+		if err != nil {
+			return nil, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case isKey && elems == nil:
+			if obj == nil {
+				obj = make(map[string]interface{})
+			}
+			obj[key] = v
+		case !isKey && obj == nil:
+			elems = append(elems, v)
+		default:
+			return nil, p.errorf("mixed keyed and unkeyed entries")
+		}
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.i++
+		case close:
+		case 0:
+			return nil, p.errorf("unexpected end of input, expected %q", close)
+		default:
+			return nil, p.errorf("expected \",\" or %q, found %q", close, p.rest())
+		}
+	}
+
+	switch {
+	case obj != nil:
+		return obj, nil
+	case elems != nil:
+		return elems, nil
+	case list || close == ']':
+		return []interface{}{}, nil
+	}
+	return map[string]interface{}{}, nil
+}
+
+// key parses the "key:" of an object entry, if there is one: a field
+// name, or a map key printed as a value. Without a key the position is
+// left at the start of the entry.
+func (p *parser) key(list bool) (string, bool, error) {
+	if list {
+		return "", false, nil
+	}
+	start := p.i
+	var key string
+	if c := p.peek(); c == '_' || unicode.IsLetter(rune(c)) {
+		key = p.ident()
+	} else {
+		v, err := p.value()
+		if err != nil {
+			return "", false, err
+		}
+		key = fmt.Sprint(v)
+	}
+	p.skipSpace()
+	if p.peek() != ':' {
+		p.i = start
+		return "", false, nil
+	}
+	p.i++
+	return key, true, nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// unmarshalValue stores the parsed value src into dst.
+func unmarshalValue(dst reflect.Value, src interface{}, path Path) error {
+	mismatch := func() error {
+		return &PathError{
+			Op:   "unmarshal",
+			Path: path.String(),
+			Err:  fmt.Errorf("%w: cannot use %s as %s", ErrTypeMismatch, Config{}.Sprint(src), dst.Type()),
+		}
+	}
+
+	if s, ok := src.(string); ok && dst.Kind() != reflect.String && reflect.PointerTo(dst.Type()).Implements(textUnmarshalerType) {
+		if err := dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return &PathError{Op: "unmarshal", Path: path.String(), Err: fmt.Errorf("%w: %v", ErrTypeMismatch, err)}
+		}
+		return nil
+	}
+	// Durations print as their String, e.g. "1.5s".
+	if s, ok := src.(string); ok && dst.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return &PathError{Op: "unmarshal", Path: path.String(), Err: fmt.Errorf("%w: %v", ErrTypeMismatch, err)}
+		}
+		dst.SetInt(int64(d))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if src == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return unmarshalValue(dst.Elem(), src, path)
+	case reflect.Interface:
+		if src == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		if !reflect.TypeOf(src).AssignableTo(dst.Type()) {
+			return mismatch()
+		}
+		dst.Set(reflect.ValueOf(src))
+		return nil
+	}
+
+	if src == nil {
+		switch dst.Kind() {
+		case reflect.Map, reflect.Slice:
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return mismatch()
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		obj, ok := src.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		for name, v := range obj {
+			field, pos, ok := jsonField(dst.Type(), name)
+			if !ok {
+				continue
+			}
+			f, ok := fieldByIndex(dst, field.index, true)
+			if !ok {
+				continue
+			}
+			elem := PathElem{Kind: FieldElem, Field: dst.Type().FieldByIndex(field.index).Name, Name: name, Index: pos}
+			if err := unmarshalValue(f, v, append(path, elem)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		obj, ok := src.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), len(obj)))
+		}
+		for k, v := range obj {
+			key, err := parseMapKey(k, dst.Type().Key())
+			if err != nil {
+				return &PathError{Op: "unmarshal", Path: path.String(), Err: err}
+			}
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := unmarshalValue(elem, v, append(path, PathElem{Kind: KeyElem, Key: k})); err != nil {
+				return err
+			}
+			dst.SetMapIndex(key, elem)
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := src.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		if dst.Kind() == reflect.Slice {
+			dst.Set(reflect.MakeSlice(dst.Type(), len(list), len(list)))
+		} else if len(list) > dst.Len() {
+			return mismatch()
+		}
+		for i, v := range list {
+			if err := unmarshalValue(dst.Index(i), v, append(path, PathElem{Kind: IndexElem, Index: i})); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := src.(int64)
+		if !ok || dst.OverflowInt(n) {
+			return mismatch()
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var n uint64
+		switch x := src.(type) {
+		case int64:
+			if x < 0 {
+				return mismatch()
+			}
+			n = uint64(x)
+		case uint64:
+			n = x
+		default:
+			return mismatch()
+		}
+		if dst.OverflowUint(n) {
+			return mismatch()
+		}
+		dst.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch x := src.(type) {
+		case float64:
+			f = x
+		case int64:
+			f = float64(x)
+		case uint64:
+			f = float64(x)
+		default:
+			return mismatch()
+		}
+		dst.SetFloat(f)
+	default:
+		return mismatch()
+	}
+	return nil
+}
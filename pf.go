@@ -6,6 +6,11 @@
 //	s := pf.Sprint(myStruct)
 //	pf.Diff(oldStruct, newStruct)
 //
+// The package-level functions use the config returned by Default, with
+// any options applied for the one call:
+//
+//	pf.Print(myStruct, pf.WithDepth(3), pf.WithColor(false))
+//
 // Implement PrettyPrinter for custom formatting:
 //
 //	func (u User) PrettyPrint() string { return fmt.Sprintf("User<%s>", u.Name) }
@@ -15,9 +20,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
 )
 
-// DefaultConfig is the default pretty-print configuration.
+// DefaultConfig is the configuration of the package-level functions
+// until SetDefault is first called.
+//
+// Deprecated: Changing DefaultConfig while other goroutines print is a
+// data race. Use SetDefault, or options such as WithColor for one call.
 var DefaultConfig = Config{
 	Indent:      "  ",
 	ShowTypes:   false,
@@ -26,36 +36,172 @@ var DefaultConfig = Config{
 	ColorMode:   true,
 }
 
+// defaultConfig holds the config set by SetDefault.
+var defaultConfig atomic.Pointer[Config]
+
+// Default returns the configuration of the package-level functions: the
+// last one passed to SetDefault, or DefaultConfig if there was none.
+func Default() Config {
+	if c := defaultConfig.Load(); c != nil {
+		return *c
+	}
+	return DefaultConfig
+}
+
+// SetDefault sets the configuration of the package-level functions. It
+// is safe to call while other goroutines print:
+//
+//	c := pf.Default()
+//	c.ColorMode = false
+//	pf.SetDefault(c)
+func SetDefault(c Config) {
+	c.Rules = append([]Rule(nil), c.Rules...)
+	defaultConfig.Store(&c)
+}
+
 // --- Pretty Print ---
 
 // Print pretty-prints to stdout.
-func Print(v interface{}) {
-	fmt.Fprintln(os.Stdout, Sprint(v))
+func Print(v interface{}, opts ...Option) {
+	fmt.Fprintln(os.Stdout, Sprint(v, opts...))
 }
 
 // Sprint returns a pretty-printed string.
-func Sprint(v interface{}) string {
-	return DefaultConfig.Sprint(v)
+func Sprint(v interface{}, opts ...Option) string {
+	return Default().With(opts...).Sprint(v)
 }
 
 // Fprint pretty-prints to the given writer.
-func Fprint(w io.Writer, v interface{}) {
-	fmt.Fprintln(w, DefaultConfig.Sprint(v))
+func Fprint(w io.Writer, v interface{}, opts ...Option) {
+	fmt.Fprintln(w, Default().With(opts...).Sprint(v))
 }
 
 // --- Diff ---
 
 // Diff prints a colorized diff to stdout.
-func Diff(a, b interface{}) {
-	fmt.Fprintln(os.Stdout, SprintDiff(a, b))
+func Diff(a, b interface{}, opts ...Option) {
+	fmt.Fprintln(os.Stdout, SprintDiff(a, b, opts...))
 }
 
 // SprintDiff returns a diff string.
-func SprintDiff(a, b interface{}) string {
-	return DefaultConfig.SprintDiff(a, b)
+func SprintDiff(a, b interface{}, opts ...Option) string {
+	return Default().With(opts...).SprintDiff(a, b)
 }
 
 // FprintDiff writes a diff to the given writer.
-func FprintDiff(w io.Writer, a, b interface{}) {
-	fmt.Fprintln(w, DefaultConfig.SprintDiff(a, b))
+func FprintDiff(w io.Writer, a, b interface{}, opts ...Option) {
+	fmt.Fprintln(w, Default().With(opts...).SprintDiff(a, b))
+}
+
+// SideBySide prints a two-column diff to stdout.
+func SideBySide(a, b interface{}, opts ...Option) {
+	fmt.Fprintln(os.Stdout, SprintSideBySide(a, b, opts...))
+}
+
+// SprintSideBySide returns a two-column diff string.
+func SprintSideBySide(a, b interface{}, opts ...Option) string {
+	return Default().With(opts...).SprintSideBySide(a, b)
+}
+
+// FprintSideBySide writes a two-column diff to the given writer.
+func FprintSideBySide(w io.Writer, a, b interface{}, opts ...Option) {
+	fmt.Fprintln(w, Default().With(opts...).SprintSideBySide(a, b))
+}
+
+// --- Table ---
+
+// Table prints a slice or map of structs or maps to stdout as a table.
+func Table(v interface{}, opts ...Option) {
+	fmt.Fprintln(os.Stdout, SprintTable(v, opts...))
+}
+
+// SprintTable returns a slice or map of structs or maps as a table.
+func SprintTable(v interface{}, opts ...Option) string {
+	return Default().With(opts...).SprintTable(v)
+}
+
+// FprintTable writes a slice or map of structs or maps to the given
+// writer as a table.
+func FprintTable(w io.Writer, v interface{}, opts ...Option) {
+	fmt.Fprintln(w, Default().With(opts...).SprintTable(v))
+}
+
+// --- Changes ---
+
+// Equal reports whether a and b have no differences, using the same
+// comparison as Diff.
+func Equal(a, b interface{}, opts ...Option) bool {
+	return Default().With(opts...).Equal(a, b)
+}
+
+// Summarize returns a summary of the changes between a and b.
+func Summarize(a, b interface{}, opts ...Option) Summary {
+	return Default().With(opts...).Summarize(a, b)
+}
+
+// Changes returns the structured list of differences between a and b.
+func Changes(a, b interface{}, opts ...Option) []Change {
+	return Default().With(opts...).Changes(a, b)
+}
+
+// Diff3 reports which of ours and theirs changed each path relative to base.
+func Diff3(base, ours, theirs interface{}, opts ...Option) []Change3 {
+	return Default().With(opts...).Diff3(base, ours, theirs)
+}
+
+// Merge3 merges the changes ours and theirs made to base, returning the
+// merged value and the conflicting paths.
+func Merge3(base, ours, theirs interface{}, opts ...Option) (interface{}, []Change3, error) {
+	return Default().With(opts...).Merge3(base, ours, theirs)
+}
+
+// --- Select ---
+
+// Select returns the values in v matching query; see Config.Select.
+func Select(v interface{}, query string, opts ...Option) ([]Match, error) {
+	return Default().With(opts...).Select(v, query)
+}
+
+// PrintPath prints the values in v matching query to stdout, each with
+// its path:
+//
+//	pf.PrintPath(customer, "Orders[*].Amount")
+func PrintPath(v interface{}, query string, opts ...Option) {
+	fmt.Fprintln(os.Stdout, SprintPath(v, query, opts...))
+}
+
+// SprintPath returns the values in v matching query, each with its path.
+func SprintPath(v interface{}, query string, opts ...Option) string {
+	return Default().With(opts...).SprintPath(v, query)
+}
+
+// FprintPath writes the values in v matching query to the given writer.
+func FprintPath(w io.Writer, v interface{}, query string, opts ...Option) {
+	fmt.Fprintln(w, Default().With(opts...).SprintPath(v, query))
+}
+
+// SprintHTML returns v pretty-printed as HTML.
+func SprintHTML(v interface{}, opts ...Option) string {
+	return Default().With(opts...).SprintHTML(v)
+}
+
+// SprintDiffHTML returns the diff between a and b as HTML.
+func SprintDiffHTML(a, b interface{}, opts ...Option) string {
+	return Default().With(opts...).SprintDiffHTML(a, b)
+}
+
+// SprintMarkdown returns v pretty-printed as Markdown.
+func SprintMarkdown(v interface{}, opts ...Option) string {
+	return Default().With(opts...).SprintMarkdown(v)
+}
+
+// SprintDiffMarkdown returns the diff between a and b as Markdown.
+func SprintDiffMarkdown(a, b interface{}, opts ...Option) string {
+	return Default().With(opts...).SprintDiffMarkdown(a, b)
+}
+
+// Explore shows v in an interactive tree viewer in the terminal. See
+// Config.Explore for the keys.
+func Explore(v interface{}, opts ...Option) error {
+	return Default().With(opts...).Explore(v)
 }
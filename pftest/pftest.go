@@ -0,0 +1,72 @@
+// Package pftest provides test assertions that report differences
+// as pf diffs.
+//
+//	func TestUser(t *testing.T) {
+//	    got := LoadUser(1)
+//	    pftest.AssertEqual(t, want, got)
+//	}
+package pftest
+
+import (
+	"os"
+	"testing"
+
+	"github.com/nd-forge/pf"
+	"github.com/nd-forge/pf/internal/term"
+)
+
+// Option customizes the config used to compare and render values. It
+// is pf.Option, so options such as pf.WithDepth can be passed too.
+type Option = pf.Option
+
+// WithConfig replaces the base config (default pf.Default()).
+// Colors are still disabled when stdout is not a terminal, and the
+// labels default to "want" and "got" if the config sets none.
+func WithConfig(c pf.Config) Option {
+	return func(dst *pf.Config) {
+		*dst = c
+	}
+}
+
+// WithLabels sets the diff header labels (default "want" and "got").
+func WithLabels(want, got string) Option {
+	return func(c *pf.Config) {
+		c.OldLabel = want
+		c.NewLabel = got
+	}
+}
+
+// AssertEqual reports a test error with a pf diff when want and got
+// differ, and returns whether they are equal.
+func AssertEqual(t testing.TB, want, got interface{}, opts ...Option) bool {
+	t.Helper()
+	c := config(opts)
+	if c.Equal(want, got) {
+		return true
+	}
+	t.Errorf("values differ:\n%s", c.SprintDiff(want, got))
+	return false
+}
+
+// RequireEqual is like AssertEqual but stops the test on mismatch.
+func RequireEqual(t testing.TB, want, got interface{}, opts ...Option) {
+	t.Helper()
+	c := config(opts)
+	if !c.Equal(want, got) {
+		t.Fatalf("values differ:\n%s", c.SprintDiff(want, got))
+	}
+}
+
+func config(opts []Option) pf.Config {
+	c := pf.Default()
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.ColorMode = c.ColorMode && term.IsTerminal(os.Stdout.Fd())
+	c.DiffSummary = true
+	if c.OldLabel == "" && c.NewLabel == "" {
+		c.OldLabel = "want"
+		c.NewLabel = "got"
+	}
+	return c
+}
@@ -0,0 +1,152 @@
+package pftest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nd-forge/pf"
+)
+
+// A test package may define its own -update flag, as hand-rolled golden
+// file tests do; pftest must not register one too, or this panics.
+var updateGolden = flag.Bool("update", false, "rewrite golden files")
+
+// recorder captures test failures instead of reporting them.
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+	r.fatal = true
+}
+
+type user struct {
+	Name string
+	Age  int
+}
+
+func TestAssertEqual(t *testing.T) {
+	r := &recorder{}
+	if !AssertEqual(r, user{Name: "A", Age: 1}, user{Name: "A", Age: 1}) {
+		t.Error("expected equal values to pass")
+	}
+	if len(r.errors) != 0 {
+		t.Errorf("unexpected errors: %v", r.errors)
+	}
+
+	if AssertEqual(r, user{Name: "A", Age: 1}, user{Name: "A", Age: 2}, WithConfig(pf.Config{Indent: "  "})) {
+		t.Error("expected different values to fail")
+	}
+	if len(r.errors) != 1 {
+		t.Fatalf("expected one error, got: %v", r.errors)
+	}
+	msg := r.errors[0]
+	for _, want := range []string{"--- want", "+++ got", "1 field modified", "- Age: 1", "+ Age: 2"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in message, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestAssertEqual_Nil(t *testing.T) {
+	err := fmt.Errorf("boom")
+	cases := []struct {
+		want, got interface{}
+		del, add  string
+	}{
+		{nil, err, "- nil", `+ error("boom")`},
+		{err, nil, `- error("boom")`, "+ nil"},
+	}
+	for _, tc := range cases {
+		r := &recorder{}
+		if AssertEqual(r, tc.want, tc.got) {
+			t.Errorf("expected %v and %v to differ", tc.want, tc.got)
+		}
+		if len(r.errors) != 1 || !strings.Contains(r.errors[0], tc.del) || !strings.Contains(r.errors[0], tc.add) {
+			t.Errorf("expected %q and %q in message, got: %v", tc.del, tc.add, r.errors)
+		}
+	}
+}
+
+func TestRequireEqual(t *testing.T) {
+	r := &recorder{}
+	RequireEqual(r, 1, 1)
+	if r.fatal {
+		t.Error("expected equal values to pass")
+	}
+	RequireEqual(r, 1, 2, WithLabels("expected", "actual"))
+	if !r.fatal || !strings.Contains(r.errors[0], "--- expected") {
+		t.Errorf("expected fatal failure with labels, got: %v", r.errors)
+	}
+}
+
+func TestAssertEqual_PfOptions(t *testing.T) {
+	r := &recorder{}
+	AssertEqual(r, user{Name: "A"}, user{Name: "B"}, WithConfig(pf.Config{}), pf.WithIndent("    "), pf.WithLabels("old", "new"))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "--- old") || !strings.Contains(r.errors[0], "\n    - Name: \"A\"") {
+		t.Errorf("pf options not applied: %v", r.errors)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	r := &recorder{TB: t}
+	Snapshot(r, "user", user{Name: "A", Age: 1})
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "-pftest.update") {
+		t.Fatalf("expected missing golden file error, got: %v", r.errors)
+	}
+
+	*update = true
+	Snapshot(r, "user", user{Name: "A", Age: 1})
+	*update = false
+
+	data, err := os.ReadFile("testdata/TestSnapshot/user.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\n  Name: \"A\",\n  Age: 1\n}\n" {
+		t.Errorf("unexpected golden file:\n%s", data)
+	}
+
+	r.errors = nil
+	Snapshot(r, "user", user{Name: "A", Age: 1})
+	if len(r.errors) != 0 {
+		t.Errorf("expected snapshot to match, got: %v", r.errors)
+	}
+
+	Snapshot(r, "user", user{Name: "A", Age: 2})
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "-   Age: [-1-]") || !strings.Contains(r.errors[0], "+   Age: {+2+}") {
+		t.Errorf("expected line diff, got: %v", r.errors)
+	}
+
+	// The test package's own -update flag rewrites golden files too.
+	*updateGolden = true
+	Snapshot(r, "user", user{Name: "A", Age: 3})
+	*updateGolden = false
+	r.errors = nil
+	Snapshot(r, "user", user{Name: "A", Age: 3})
+	if len(r.errors) != 0 {
+		t.Errorf("expected -update to rewrite the golden file, got: %v", r.errors)
+	}
+}
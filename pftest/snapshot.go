@@ -0,0 +1,75 @@
+package pftest
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nd-forge/pf"
+)
+
+// update is pftest's own flag, so that it cannot clash with an -update
+// flag the test package defines, which is honoured too.
+var update = flag.Bool("pftest.update", false, "rewrite pftest golden files")
+
+// updating reports whether golden files are to be rewritten: with
+// -pftest.update, or -update if the test package defines it.
+func updating() bool {
+	if *update {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if g, ok := f.Value.(flag.Getter); ok {
+			b, _ := g.Get().(bool)
+			return b
+		}
+	}
+	return false
+}
+
+// snapshotConfig renders snapshots independently of pf.Default,
+// so golden files do not change with global settings.
+var snapshotConfig = pf.Config{Indent: "  "}
+
+// Snapshot pretty-prints value and compares it with the golden file
+// testdata/<test name>/<name>.golden, reporting a pf diff on mismatch.
+// Run the test with -pftest.update, or -update if the test package
+// defines that flag, to create or rewrite the golden file.
+func Snapshot(t testing.TB, name string, value interface{}) {
+	t.Helper()
+	got := snapshotConfig.Sprint(value) + "\n"
+	path := filepath.Join("testdata", filepath.FromSlash(t.Name()), name+".golden")
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("pftest: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("pftest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("pftest: golden file %s does not exist; run with -pftest.update to create it", path)
+		return
+	}
+	if err != nil {
+		t.Fatalf("pftest: %v", err)
+	}
+	if string(want) == got {
+		return
+	}
+
+	c := config(nil)
+	c.OldLabel = path
+	c.NewLabel = "got"
+	c.DiffSummary = false
+	t.Errorf("snapshot %s differs (run with -pftest.update to accept):\n%s", name,
+		c.SprintDiff(strings.TrimSuffix(string(want), "\n"), strings.TrimSuffix(got, "\n")))
+}
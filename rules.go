@@ -0,0 +1,208 @@
+package pf
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// RuleAction is what a Rule does to the values it matches.
+type RuleAction int
+
+const (
+	// Collapse prints a struct, map or slice as a one-line summary such
+	// as {…12 fields} or […340 items].
+	Collapse RuleAction = iota + 1
+	// Expand prints a value in full, ignoring MaxDepth.
+	Expand
+)
+
+// Rule expands or collapses the values matching its Path and Type. A
+// rule with both set matches values matching both.
+//
+//	cfg.Rules = []pf.Rule{
+//		{Type: "*sql.DB", Action: pf.Collapse},
+//		{Type: "context.Context", Action: pf.Collapse},
+//		{Path: "Request.Body", Action: pf.Expand},
+//	}
+type Rule struct {
+	// Path is a query in the syntax of Select, e.g. "Request.Body",
+	// "Items[*].Raw" or "..Token". A Path that is not a valid query
+	// matches nothing.
+	Path string
+	// Type is a type as printed by reflect.Type.String, e.g. "*sql.DB"
+	// or "[]byte". Interface types such as "context.Context" match
+	// values stored in fields, elements or map values of that type.
+	Type string
+	// Action is Collapse or Expand.
+	Action RuleAction
+}
+
+// compiledRule is a Rule with its Path parsed.
+type compiledRule struct {
+	Rule
+	steps []queryStep
+}
+
+func compileRules(rules []Rule) []compiledRule {
+	var out []compiledRule
+	for _, r := range rules {
+		if r.Path == "" && r.Type == "" {
+			continue
+		}
+		steps, err := parseQuery(r.Path)
+		if err != nil {
+			continue
+		}
+		out = append(out, compiledRule{Rule: r, steps: steps})
+	}
+	return out
+}
+
+func (r *compiledRule) matches(path []child, v reflect.Value) bool {
+	if r.Path != "" && !matchPath(r.steps, path) {
+		return false
+	}
+	return r.Type == "" || matchType(r.Type, v)
+}
+
+// matchPath reports whether path matches the query steps exactly.
+func matchPath(steps []queryStep, path []child) bool {
+	if len(steps) == 0 {
+		return len(path) == 0
+	}
+	if steps[0].kind == stepDescend {
+		for i := 0; i <= len(path); i++ {
+			if matchPath(steps[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(path) > 0 && steps[0].matches(path[0]) && matchPath(steps[1:], path[1:])
+}
+
+// leadsTo reports whether a longer path starting with path may match
+// the query steps. Queries with ".." are not considered, since they
+// could match below any value.
+func leadsTo(steps []queryStep, path []child) bool {
+	if len(steps) <= len(path) {
+		return false
+	}
+	for i, c := range path {
+		if steps[i].kind == stepDescend || !steps[i].matches(c) {
+			return false
+		}
+	}
+	return steps[len(path)].kind != stepDescend
+}
+
+// matchType reports whether v, or a value it points to or holds, has
+// the type named typ.
+func matchType(typ string, v reflect.Value) bool {
+	for v.IsValid() {
+		if v.Type().String() == typ {
+			return true
+		}
+		if (v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface) || v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	return false
+}
+
+// ruleAction returns the action of the last rule matching v at the
+// current path, or 0 if none does. Map keys match no rules.
+func (f *formatter) ruleAction(v reflect.Value) RuleAction {
+	if len(f.rules) == 0 || f.inKey {
+		return 0
+	}
+	var action RuleAction
+	for i := range f.rules {
+		if f.rules[i].matches(f.path, v) {
+			action = f.rules[i].Action
+		}
+	}
+	return action
+}
+
+// leadsToExpand reports whether an Expand rule matches a value below
+// the current path, which must then be printed despite MaxDepth.
+func (f *formatter) leadsToExpand() bool {
+	if f.inKey {
+		return false
+	}
+	for _, r := range f.rules {
+		if r.Action == Expand && r.Path != "" && r.Type == "" && leadsTo(r.steps, f.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatChild formats v, a field, map value or element of the value
+// being formatted. elem describes it for rules and MaskSecrets, and is
+// only called when they are in use.
+func (f *formatter) formatChild(v reflect.Value, depth int, elem func() child) {
+	if len(f.rules) == 0 && !f.config.MaskSecrets {
+		f.format(v, depth)
+		return
+	}
+	f.path = append(f.path, elem())
+	f.format(v, depth)
+	f.path = f.path[:len(f.path)-1]
+}
+
+// formatCollapsed writes the one-line summary of a struct, map, slice
+// or array.
+func (f *formatter) formatCollapsed(v reflect.Value) {
+	var open, close, count string
+	switch v.Kind() {
+	case reflect.Struct:
+		if f.config.ShowTypes {
+			f.colored(cType, v.Type().Name()+" ")
+		}
+		n := visibleFields(v, f.config)
+		open, close, count = "{", "}", plural(n, "field")
+		if n == 0 {
+			count = ""
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			f.colored(cNil, "nil")
+			return
+		}
+		if f.config.ShowTypes {
+			f.colored(cType, fmt.Sprintf("map[%s]%s ", v.Type().Key(), v.Type().Elem()))
+		}
+		open, close = "{", "}"
+		if v.Len() > 0 {
+			count = plural(v.Len(), "entry")
+		}
+	default:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			f.colored(cNil, "nil")
+			return
+		}
+		open, close = "[", "]"
+		if v.Len() > 0 {
+			count = plural(v.Len(), "item")
+		}
+	}
+	f.colored(cBrace, open)
+	if count != "" {
+		f.colored(cType, "…"+count)
+	}
+	f.colored(cBrace, close)
+}
+
+// plural returns "1 field", "2 fields", "3 entries".
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	if noun == "entry" {
+		return fmt.Sprintf("%d entries", n)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
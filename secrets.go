@@ -0,0 +1,174 @@
+package pf
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// masked is printed in place of a value that looks like a secret.
+const masked = "<masked>"
+
+// secretNames are the words that make a field or map key name a
+// secret's, compared without case, underscores or dashes.
+var secretNames = []string{
+	"password", "passwd", "passphrase", "secret", "token", "authorization",
+	"apikey", "accesskey", "privatekey", "credential",
+}
+
+// secretPatterns match strings that hold a secret whatever they are
+// called: JWTs, AWS access key IDs and PEM blocks.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`eyJ[\w-]+\.eyJ[\w-]+\.[\w-]+`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----`),
+}
+
+// digitRuns matches runs of digit groups separated by single spaces or
+// dashes, in which card numbers are looked for.
+var digitRuns = regexp.MustCompile(`\b\d+(?:[ -]\d+)*\b`)
+
+// isSecretName reports whether a field or key called name likely holds
+// a secret, e.g. Password, api_key or X-Auth-Token.
+func isSecretName(name string) bool {
+	name = strings.Map(func(r rune) rune {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+	for _, s := range secretNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksSecret reports whether s contains a JWT, an AWS access key ID, a
+// PEM block or a card number.
+func looksSecret(s string) bool {
+	for _, re := range secretPatterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	for _, run := range digitRuns.FindAllString(s, -1) {
+		if containsCard(run) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsCard reports whether consecutive groups of a digit run, such
+// as "2024-01-01 4111 1111 1111 1111", form a card number: 13 to 19
+// digits starting with 2 to 6, like those of the card networks, that
+// pass the Luhn check.
+func containsCard(run string) bool {
+	groups := strings.FieldsFunc(run, func(r rune) bool { return r == ' ' || r == '-' })
+	for i := range groups {
+		if groups[i][0] < '2' || groups[i][0] > '6' {
+			continue
+		}
+		n := 0
+		for j := i; j < len(groups) && n <= 19; j++ {
+			n += len(groups[j])
+			if n >= 13 && n <= 19 && luhn(strings.Join(groups[i:j+1], "")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// luhn reports whether the digits in s, 13 to 19 of them, pass the Luhn
+// checksum of card numbers. Other characters are ignored.
+func luhn(s string) bool {
+	var digits []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// isSecret reports whether v, a value named name, should be masked: a
+// non-empty string or byte slice with a secret name, or a string that
+// looks like a secret. Other kinds are left alone, so that a field such
+// as MaxTokens still prints.
+func isSecret(name string, v reflect.Value) bool {
+	v = unwrapValue(v)
+	if !v.IsValid() {
+		return false
+	}
+	var s string
+	switch {
+	case v.Kind() == reflect.String:
+		s = v.String()
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		s = string(v.Bytes())
+	default:
+		return false
+	}
+	return s != "" && (isSecretName(name) || looksSecret(s))
+}
+
+// secretName returns the name of the field or map key the value being
+// formatted is, or is an element of, or "" for a map key.
+func (f *formatter) secretName() string {
+	if f.inKey {
+		return ""
+	}
+	for i := len(f.path) - 1; i >= 0; i-- {
+		if c := f.path[i]; c.elem.Kind != IndexElem {
+			return strings.Join(c.names, " ")
+		}
+	}
+	return f.name
+}
+
+// maskText writes the masked placeholder in place of s, the output of a
+// PrettyPrint, String or Error method, if MaskSecrets is set and s
+// looks like a secret or is named like one.
+func (f *formatter) maskText(s string) bool {
+	if !f.config.MaskSecrets || s == "" || !isSecretName(f.secretName()) && !looksSecret(s) {
+		return false
+	}
+	f.colored(cType, masked)
+	return true
+}
+
+// maskSecret writes the masked placeholder in place of v if MaskSecrets
+// is set and v looks like a secret.
+func (f *formatter) maskSecret(v reflect.Value) bool {
+	if !f.config.MaskSecrets || !isSecret(f.secretName(), v) {
+		return false
+	}
+	f.colored(cType, masked)
+	return true
+}
+
+// sprintNamed is Sprint for a value named name, such as a slog attribute
+// or a diffed field, so that MaskSecrets can see its name.
+func (c Config) sprintNamed(name string, v interface{}) string {
+	f := c.newFormatter()
+	f.name = name
+	f.format(reflect.ValueOf(v), 0)
+	return f.sb.String()
+}
@@ -0,0 +1,322 @@
+package pf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Match is a value found by Select, with its path from the root.
+type Match struct {
+	Path  Path
+	Value interface{}
+}
+
+// Select returns the values in v matching query, in field/key/index
+// order. A query is a path like Path.String renders, with wildcards and
+// recursive descent:
+//
+//	Orders[0].Amount     field, index, field
+//	Meta["role"]         map key (also Meta.role or Meta[role])
+//	Orders[*].Amount     any element, key or field
+//	Orders.*             same as Orders[*]
+//	..Amount             Amount at any depth
+//
+// Field names match either the Go name or the json tag name. An empty
+// query or "." selects v itself. Pointer cycles are not followed twice.
+func (c Config) Select(v interface{}, query string) ([]Match, error) {
+	s, err := c.selectQuery(v, query)
+	if err != nil {
+		return nil, err
+	}
+	return s.matches, nil
+}
+
+func (c Config) selectQuery(v interface{}, query string) (*selector, error) {
+	steps, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	s := &selector{config: c, seen: make(map[string]bool), visiting: make(map[uintptr]bool)}
+	s.walk(reflect.ValueOf(v), nil, steps)
+	return s, nil
+}
+
+// SprintPath returns the values in v matching query (see Select), one
+// "path: value" entry per match. It returns "" if nothing matches and
+// the error, formatted like an error value, if query is invalid.
+func (c Config) SprintPath(v interface{}, query string) string {
+	s, err := c.selectQuery(v, query)
+	if err != nil {
+		return c.Sprint(err)
+	}
+	var sb strings.Builder
+	for i, m := range s.matches {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if p := m.Path.String(); p != "" {
+			sb.WriteString(c.paint(cKey, p, false))
+			sb.WriteString(": ")
+		}
+		// Rules match paths from the root of v.
+		f := c.newFormatter()
+		f.path = s.chains[i]
+		f.format(reflect.ValueOf(m.Value), 0)
+		sb.WriteString(f.sb.String())
+	}
+	return sb.String()
+}
+
+// PrintPath prints the values in v matching query to stdout using this
+// config.
+func (c Config) PrintPath(v interface{}, query string) {
+	fmt.Fprintln(os.Stdout, c.SprintPath(v, query))
+}
+
+// FprintPath writes the values in v matching query to w using this
+// config.
+func (c Config) FprintPath(w io.Writer, v interface{}, query string) {
+	fmt.Fprintln(w, c.SprintPath(v, query))
+}
+
+// queryKind identifies the kind of a query step.
+type queryKind int
+
+const (
+	// stepName selects a struct field or a map key by name.
+	stepName queryKind = iota
+	// stepIndex selects a slice element by index or a map key.
+	stepIndex
+	// stepAny selects every field, key or element.
+	stepAny
+	// stepDescend applies the following steps at any depth.
+	stepDescend
+)
+
+type queryStep struct {
+	kind queryKind
+	name string
+}
+
+func parseQuery(query string) ([]queryStep, error) {
+	invalid := func(msg string) error {
+		return fmt.Errorf("pf: invalid path %q: %s", query, msg)
+	}
+
+	var steps []queryStep
+	rest := query
+	if rest == "." {
+		return nil, nil
+	}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			rest = rest[2:]
+			if rest == "" || rest[0] == '.' {
+				return nil, invalid("expected a name after ..")
+			}
+			steps = append(steps, queryStep{kind: stepDescend})
+			continue
+		case rest[0] == '.':
+			rest = rest[1:]
+			if rest == "" || rest[0] == '[' {
+				return nil, invalid("expected a name after .")
+			}
+		case strings.HasPrefix(rest, `["`):
+			// A quoted key may contain "]" or ".".
+			n, key, ok := scanGoString(rest[1:])
+			if !ok || !strings.HasPrefix(rest[1+n:], "]") {
+				return nil, invalid("bad quoted key")
+			}
+			steps = append(steps, queryStep{kind: stepIndex, name: key})
+			rest = rest[n+2:]
+			continue
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, invalid("missing ]")
+			}
+			switch inner := rest[1:end]; inner {
+			case "":
+				return nil, invalid("empty []")
+			case "*":
+				steps = append(steps, queryStep{kind: stepAny})
+			default:
+				steps = append(steps, queryStep{kind: stepIndex, name: inner})
+			}
+			rest = rest[end+1:]
+			continue
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		switch name := rest[:end]; name {
+		case "":
+			return nil, invalid("empty name")
+		case "*":
+			steps = append(steps, queryStep{kind: stepAny})
+		default:
+			steps = append(steps, queryStep{kind: stepName, name: name})
+		}
+		rest = rest[end:]
+	}
+	return steps, nil
+}
+
+// scanGoString parses the double-quoted Go string at the start of s,
+// returning its length in bytes and its value.
+func scanGoString(s string) (int, string, bool) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			v, err := strconv.Unquote(s[:i+1])
+			return i + 1, v, err == nil
+		}
+	}
+	return 0, "", false
+}
+
+type selector struct {
+	config  Config
+	matches []Match
+	// chains holds the path of each match as children, for rules.
+	chains [][]child
+	// seen holds the paths already matched, since recursive descent
+	// can reach the same value through several steps.
+	seen map[string]bool
+	// visiting holds the pointers on the current walk, to stop cycles.
+	visiting map[uintptr]bool
+}
+
+// child is a field, key or element of a value, with the names a query
+// step may use for it.
+type child struct {
+	value reflect.Value
+	elem  PathElem
+	// names holds the Go and json names of a field, or the printed key.
+	names []string
+}
+
+// fieldChild returns the field of a struct of type t at position pos
+// of its printed fields (Config.structFields). v is its value.
+func fieldChild(t reflect.Type, pos int, field structField, v reflect.Value, c Config) child {
+	sf := t.FieldByIndex(field.index)
+	names := []string{sf.Name}
+	if tf, ok := tagField(sf, nil); ok && tf.tagged {
+		names = append(names, tf.name)
+	}
+	return child{
+		value: v,
+		elem:  PathElem{Kind: FieldElem, Field: sf.Name, Name: c.fieldName(sf), Index: pos},
+		names: names,
+	}
+}
+
+func keyChild(k, v reflect.Value) child {
+	return child{
+		value: v,
+		elem:  PathElem{Kind: KeyElem, Key: k.Interface()},
+		names: []string{fmt.Sprint(k.Interface())},
+	}
+}
+
+func indexChild(i int, v reflect.Value) child {
+	return child{
+		value: v,
+		elem:  PathElem{Kind: IndexElem, Index: i},
+		names: []string{strconv.Itoa(i)},
+	}
+}
+
+func (s *selector) walk(v reflect.Value, chain []child, steps []queryStep) {
+	if len(steps) == 0 {
+		path := make(Path, len(chain))
+		for i, c := range chain {
+			path[i] = c.elem
+		}
+		if p := path.String(); !s.seen[p] {
+			s.seen[p] = true
+			s.matches = append(s.matches, Match{Path: path, Value: interfaceOf(v)})
+			s.chains = append(s.chains, append([]child(nil), chain...))
+		}
+		return
+	}
+
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.IsValid() && v.Kind() == reflect.Ptr && !v.IsNil() {
+		p := v.Pointer()
+		if s.visiting[p] {
+			return
+		}
+		s.visiting[p] = true
+		defer delete(s.visiting, p)
+	}
+
+	step := steps[0]
+	if step.kind == stepDescend {
+		s.walk(v, chain, steps[1:])
+		for _, c := range s.children(v) {
+			s.walk(c.value, append(chain, c), steps)
+		}
+		return
+	}
+	for _, c := range s.children(v) {
+		if step.matches(c) {
+			s.walk(c.value, append(chain, c), steps[1:])
+		}
+	}
+}
+
+func (st queryStep) matches(c child) bool {
+	switch st.kind {
+	case stepAny:
+		return true
+	case stepName:
+		if c.elem.Kind == IndexElem {
+			return false
+		}
+	}
+	for _, n := range c.names {
+		if n == st.name {
+			return true
+		}
+	}
+	return false
+}
+
+// children lists the fields, keys or elements of v in print order.
+func (s *selector) children(v reflect.Value) []child {
+	v = unwrapValue(v)
+	if !v.IsValid() || isLeaf(v) {
+		return nil
+	}
+	var out []child
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for pos, field := range s.config.structFields(t) {
+			if fv, err := v.FieldByIndexErr(field.index); err == nil && !field.omitted(fv) {
+				out = append(out, fieldChild(t, pos, field, fv, s.config))
+			}
+		}
+	case reflect.Map:
+		for _, k := range sortMapKeys(v.MapKeys()) {
+			out = append(out, keyChild(k, v.MapIndex(k)))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, indexChild(i, v.Index(i)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package pf
+
+import "strings"
+
+// sideBySide renders two values as aligned old/new columns.
+type sideBySide struct {
+	config Config
+	sb     strings.Builder
+}
+
+// render pretty-prints a and b, aligns their lines, and writes them as
+// two columns separated by a gutter marker:
+//
+//	" " unchanged, "|" changed, "<" only in a, ">" only in b.
+func (s *sideBySide) render(a, b interface{}) string {
+	plain := s.config
+	plain.ColorMode = false
+	plain.Width = 0 // one value per line keeps the columns aligned
+	plain.Rules = nil
+	left := splitLines(plain.Sprint(a))
+	right := splitLines(plain.Sprint(b))
+
+	col := (s.config.width() - 3) / 2
+	if col < 10 {
+		col = 10
+	}
+
+	if s.config.OldLabel != "" || s.config.NewLabel != "" {
+		s.writeRow(col, s.config.OldLabel, " ", s.config.NewLabel, false, false)
+		s.sb.WriteString(strings.Repeat("-", col) + "   " + strings.Repeat("-", col) + "\n")
+	}
+
+	edits := diffTokens(left, right)
+	for i := 0; i < len(edits); {
+		if edits[i].op == opEqual {
+			s.writeRow(col, edits[i].text, " ", edits[i].text, false, false)
+			i++
+			continue
+		}
+		var dels, adds []string
+		for ; i < len(edits) && edits[i].op == opDelete; i++ {
+			dels = append(dels, edits[i].text)
+		}
+		for ; i < len(edits) && edits[i].op == opInsert; i++ {
+			adds = append(adds, edits[i].text)
+		}
+		for j := 0; j < len(dels) || j < len(adds); j++ {
+			switch {
+			case j < len(dels) && j < len(adds):
+				s.writeRow(col, dels[j], "|", adds[j], true, true)
+			case j < len(dels):
+				s.writeRow(col, dels[j], "<", "", true, false)
+			default:
+				s.writeRow(col, "", ">", adds[j], false, true)
+			}
+		}
+	}
+	return strings.TrimSuffix(s.sb.String(), "\n")
+}
+
+func (s *sideBySide) writeRow(col int, left, marker, right string, delChanged, addChanged bool) {
+	left = padRight(truncateWidth(left, col), col)
+	right = truncateWidth(right, col)
+
+	if delChanged {
+		left = s.config.paint(cDiffDel, left, true)
+	}
+	if addChanged {
+		right = s.config.paint(cDiffAdd, right, true)
+	}
+	if marker != " " {
+		marker = s.config.paint(cType, marker, true)
+	}
+
+	s.sb.WriteString(left)
+	s.sb.WriteString(" ")
+	s.sb.WriteString(marker)
+	s.sb.WriteString(" ")
+	s.sb.WriteString(right)
+	s.sb.WriteString("\n")
+}
@@ -0,0 +1,233 @@
+package pf
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// trySlog formats slog.Value and slog.LogValuer values by their
+// resolved slog value. Returns true if iface was one of them.
+func (f *formatter) trySlog(iface interface{}, depth int) bool {
+	var val slog.Value
+	switch x := iface.(type) {
+	case slog.Value:
+		val = x
+	case slog.LogValuer:
+		val = slog.AnyValue(x)
+	default:
+		return false
+	}
+	f.formatSlogValue(val.Resolve(), depth)
+	return true
+}
+
+func (f *formatter) formatSlogValue(val slog.Value, depth int) {
+	switch val.Kind() {
+	case slog.KindGroup:
+		f.formatAttrs(val.Group(), depth)
+	case slog.KindTime:
+		f.colored(cString, `"`+val.Time().Format(time.RFC3339Nano)+`"`)
+	default:
+		f.format(reflect.ValueOf(val.Any()), depth)
+	}
+}
+
+// formatAttrs formats a slog group like a struct, keyed by attr key.
+func (f *formatter) formatAttrs(attrs []slog.Attr, depth int) {
+	if len(attrs) == 0 {
+		f.colored(cBrace, "{}")
+		return
+	}
+	if f.config.Width > 0 && !f.singleLine && !f.tree() && f.writeCompact(func(sub *formatter) { sub.formatAttrs(attrs, depth) }) {
+		return
+	}
+	if f.tree() {
+		// Trees label blocks by type, as for structs.
+		f.colored(cType, slog.KindGroup.String())
+	}
+
+	f.openBlock("{")
+	for i, a := range attrs {
+		f.entryIndent(depth, i, len(attrs))
+		f.colored(cKey, a.Key)
+		f.sb.WriteString(": ")
+		if len(f.rules) == 0 && !f.config.MaskSecrets {
+			f.formatSlogValue(a.Value.Resolve(), depth+1)
+		} else {
+			// Attributes are in the path like map entries.
+			f.path = append(f.path, child{elem: PathElem{Kind: KeyElem, Key: a.Key}, names: []string{a.Key}})
+			f.formatSlogValue(a.Value.Resolve(), depth+1)
+			f.path = f.path[:len(f.path)-1]
+		}
+		f.endEntry(i, len(attrs))
+	}
+	f.closeBlock("}", depth)
+}
+
+// SlogHandlerOptions configures a SlogHandler.
+type SlogHandlerOptions struct {
+	// Level is the minimum level to log (default slog.LevelInfo).
+	Level slog.Leveler
+	// Config formats attribute values (nil = Default() at log time).
+	Config *Config
+	// TimeFormat formats record times (default "15:04:05.000").
+	TimeFormat string
+}
+
+// SlogHandler is a slog.Handler for development logs. Each record is
+// written as one line with its time, level, message and simple
+// attributes; structs, maps, slices and groups follow as pf-formatted
+// blocks:
+//
+//	12:00:00.000 INFO user logged in attempt=3
+//	  user: {
+//	    Name: "John",
+//	    Age: 30
+//	  }
+type SlogHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	opts   SlogHandlerOptions
+	attrs  []slog.Attr
+	groups []string
+}
+
+// NewSlogHandler returns a SlogHandler writing to w. opts may be nil.
+func NewSlogHandler(w io.Writer, opts *SlogHandlerOptions) *SlogHandler {
+	h := &SlogHandler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.TimeFormat == "" {
+		h.opts.TimeFormat = "15:04:05.000"
+	}
+	return h
+}
+
+// Enabled reports whether records at level are logged.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append(append([]slog.Attr(nil), h.attrs...), h.qualify(attrs)...)
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies later attribute keys with name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(append([]string(nil), h.groups...), name)
+	return &h2
+}
+
+// Handle formats and writes r.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	c := Default()
+	if h.opts.Config != nil {
+		c = *h.opts.Config
+	}
+
+	var line, blocks strings.Builder
+	if !r.Time.IsZero() {
+		line.WriteString(c.paint(cType, r.Time.Format(h.opts.TimeFormat), false))
+		line.WriteString(" ")
+	}
+	line.WriteString(levelString(r.Level, c))
+	line.WriteString(" ")
+	line.WriteString(r.Message)
+
+	attrs := append([]slog.Attr(nil), h.attrs...)
+	var recAttrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		recAttrs = append(recAttrs, a)
+		return true
+	})
+	attrs = append(attrs, h.qualify(recAttrs)...)
+
+	for _, a := range flattenAttrs(attrs) {
+		s := c.sprintNamed(a.Key, a.Value)
+		if !strings.Contains(s, "\n") {
+			line.WriteString(" ")
+			line.WriteString(c.paint(cKey, a.Key, false))
+			line.WriteString("=")
+			line.WriteString(s)
+			continue
+		}
+		blocks.WriteString(c.Indent)
+		blocks.WriteString(c.paint(cKey, a.Key, false))
+		blocks.WriteString(": ")
+		blocks.WriteString(strings.ReplaceAll(s, "\n", "\n"+c.Indent))
+		blocks.WriteString("\n")
+	}
+	line.WriteString("\n")
+	line.WriteString(blocks.String())
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line.String())
+	return err
+}
+
+// qualify prefixes attribute keys with the handler's open groups.
+func (h *SlogHandler) qualify(attrs []slog.Attr) []slog.Attr {
+	if len(h.groups) == 0 {
+		return attrs
+	}
+	prefix := strings.Join(h.groups, ".") + "."
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = slog.Attr{Key: prefix + a.Key, Value: a.Value}
+	}
+	return out
+}
+
+// flattenAttrs resolves values, drops empty attributes and inlines
+// groups with an empty key, as slog handlers are expected to.
+func flattenAttrs(attrs []slog.Attr) []slog.Attr {
+	var out []slog.Attr
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			if len(a.Value.Group()) == 0 {
+				continue
+			}
+			if a.Key == "" {
+				out = append(out, flattenAttrs(a.Value.Group())...)
+				continue
+			}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func levelString(l slog.Level, c Config) string {
+	color := cType
+	switch {
+	case l >= slog.LevelError:
+		color = cNil
+	case l >= slog.LevelWarn:
+		color = cNumber
+	case l >= slog.LevelInfo:
+		color = cKey
+	}
+	return c.paint(color, l.String(), false)
+}
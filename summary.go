@@ -0,0 +1,78 @@
+package pf
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Summary describes the changes between two values.
+type Summary struct {
+	Changes []Change
+}
+
+// Summarize returns a summary of the changes between a and b using this
+// config.
+func (c Config) Summarize(a, b interface{}) Summary {
+	return Summary{Changes: c.Changes(a, b)}
+}
+
+// Count returns the number of changes of the given kind whose last path
+// element is of the given kind. Changes to a top-level value have no
+// path element and are counted with elem 0.
+func (s Summary) Count(kind ChangeKind, elem PathKind) int {
+	n := 0
+	for _, ch := range s.Changes {
+		if ch.Kind == kind && lastElemKind(ch.Path) == elem {
+			n++
+		}
+	}
+	return n
+}
+
+// Paths returns the changed paths in order.
+func (s Summary) Paths() []string {
+	paths := make([]string, len(s.Changes))
+	for i, ch := range s.Changes {
+		paths[i] = ch.Path.String()
+	}
+	return paths
+}
+
+// String renders the counts on one line, e.g.
+// "3 fields modified, 1 key added, 2 elements removed".
+func (s Summary) String() string {
+	if len(s.Changes) == 0 {
+		return "no differences"
+	}
+	nouns := []struct {
+		elem             PathKind
+		singular, plural string
+	}{
+		{FieldElem, "field", "fields"},
+		{KeyElem, "key", "keys"},
+		{IndexElem, "element", "elements"},
+		{0, "value", "values"},
+	}
+	var parts []string
+	for _, kind := range []ChangeKind{Modified, Added, Removed} {
+		for _, n := range nouns {
+			count := s.Count(kind, n.elem)
+			if count == 0 {
+				continue
+			}
+			noun := n.plural
+			if count == 1 {
+				noun = n.singular
+			}
+			parts = append(parts, strconv.Itoa(count)+" "+noun+" "+kind.String())
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func lastElemKind(p Path) PathKind {
+	if len(p) == 0 {
+		return 0
+	}
+	return p[len(p)-1].Kind
+}
@@ -0,0 +1,304 @@
+package pf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// maxCellWidth is the width at which table cells are truncated.
+const maxCellWidth = 40
+
+// SprintTable renders a slice or array of structs or maps, or a map of
+// structs or maps, as a table with one row per element and one column
+// per field or key. Map elements get a first column holding their key.
+// Other values are pretty-printed as usual.
+func (c Config) SprintTable(v interface{}) string {
+	f := c.newFormatter()
+	if t, ok := f.buildTable(reflect.ValueOf(v)); ok {
+		f.writeTable(t)
+		return f.sb.String()
+	}
+	f.format(reflect.ValueOf(v), 0)
+	return f.sb.String()
+}
+
+// table is the content of a rendered table.
+type table struct {
+	headers []string
+	rows    [][]cell
+	// numeric marks the columns holding only numbers and nils, which
+	// are right-aligned.
+	numeric []bool
+}
+
+type cell struct {
+	text  string
+	color string
+}
+
+// buildTable collects the rows and columns of v, reporting false if v
+// is not a non-empty slice, array or map of structs or maps.
+func (f *formatter) buildTable(v reflect.Value) (*table, bool) {
+	v = unwrapValue(v)
+	if !v.IsValid() || !isCompositeKind(v.Kind()) || v.Kind() == reflect.Struct || v.Len() == 0 {
+		return nil, false
+	}
+	var keys, elems []reflect.Value
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elems = append(elems, v.Index(i))
+		}
+	case reflect.Map:
+		keys = sortMapKeys(v.MapKeys())
+		for _, k := range keys {
+			elems = append(elems, v.MapIndex(k))
+		}
+	}
+
+	// Elements must be structs of one type, or maps; nil pointers and
+	// interfaces become empty rows.
+	var structType reflect.Type
+	isMap := false
+	for _, e := range elems {
+		e = unwrapValue(e)
+		if !e.IsValid() {
+			continue
+		}
+		switch {
+		case e.Kind() == reflect.Struct && !hasPrinter(e) && !isMap && (structType == nil || structType == e.Type()):
+			structType = e.Type()
+		case e.Kind() == reflect.Map && !hasPrinter(e) && structType == nil:
+			isMap = true
+		default:
+			return nil, false
+		}
+	}
+	if structType == nil && !isMap {
+		return nil, false
+	}
+
+	t := &table{}
+	if keys != nil {
+		t.headers = append(t.headers, "")
+	}
+	var fields []structField
+	var columns []string
+	if structType != nil {
+		fields = f.config.structFields(structType)
+		for _, sf := range fields {
+			columns = append(columns, sf.name)
+			t.headers = append(t.headers, sf.name)
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, e := range elems {
+			if e = unwrapValue(e); e.IsValid() {
+				for _, k := range e.MapKeys() {
+					if name := fmt.Sprint(k.Interface()); !seen[name] {
+						seen[name] = true
+						columns = append(columns, name)
+					}
+				}
+			}
+		}
+		sort.Strings(columns)
+		t.headers = append(t.headers, columns...)
+	}
+
+	for i, e := range elems {
+		var row []cell
+		if keys != nil {
+			row = append(row, f.cell(keys[i], ""))
+		}
+		e = unwrapValue(e)
+		switch {
+		case !e.IsValid():
+			row = append(row, cell{text: "nil", color: cNil})
+		case structType != nil:
+			for _, sf := range fields {
+				if fv, ok := sf.value(e); ok {
+					row = append(row, f.cell(fv, sf.name))
+				} else {
+					row = append(row, cell{})
+				}
+			}
+		default:
+			byName := make(map[string]reflect.Value, e.Len())
+			for _, k := range e.MapKeys() {
+				byName[fmt.Sprint(k.Interface())] = e.MapIndex(k)
+			}
+			for _, name := range columns {
+				if mv, ok := byName[name]; ok {
+					row = append(row, f.cell(mv, name))
+				} else {
+					row = append(row, cell{})
+				}
+			}
+		}
+		for len(row) < len(t.headers) {
+			row = append(row, cell{})
+		}
+		t.rows = append(t.rows, row)
+	}
+
+	t.numeric = make([]bool, len(t.headers))
+	for col := range t.headers {
+		numbers, other := false, false
+		for _, row := range t.rows {
+			switch c := row[col]; {
+			case c.color == cNumber:
+				numbers = true
+			case c.text != "" && c.color != cNil:
+				other = true
+			}
+		}
+		t.numeric[col] = numbers && !other
+	}
+	return t, true
+}
+
+// cell renders v on one line for a table: strings without quotes,
+// values that format themselves through their method, and anything
+// else as a single-line pretty-print, truncated to maxCellWidth. name is
+// the column, for MaskSecrets.
+func (f *formatter) cell(v reflect.Value, name string) cell {
+	if f.config.MaskSecrets && isSecret(name, v) {
+		return cell{text: masked, color: cType}
+	}
+	u := unwrapValue(v)
+	if !u.IsValid() {
+		return cell{text: "nil", color: cNil}
+	}
+	var c cell
+	if s, ok := textOf(u); ok {
+		if f.config.MaskSecrets && (looksSecret(s) || s != "" && isSecretName(name)) {
+			return cell{text: masked, color: cType}
+		}
+		c = cell{text: s, color: cString}
+	} else {
+		switch k := u.Kind(); {
+		case k == reflect.String:
+			c = cell{text: u.String(), color: cString}
+		case k == reflect.Bool:
+			c.color = cBool
+		case k >= reflect.Int && k <= reflect.Float64:
+			c.color = cNumber
+		}
+		if c.color != cString {
+			plain := f.config
+			plain.ColorMode = false
+			sub := &formatter{config: plain, singleLine: true}
+			sub.format(u, 0)
+			c.text = sub.sb.String()
+		}
+	}
+	c.text = cellEscaper.Replace(c.text)
+	c.text = truncateWidth(c.text, maxCellWidth)
+	return c
+}
+
+var cellEscaper = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// textOf returns the output of v's PrettyPrint, String or Error method.
+func textOf(v reflect.Value) (string, bool) {
+	if !v.CanInterface() {
+		return "", false
+	}
+	switch x := v.Interface().(type) {
+	case PrettyPrinter:
+		return x.PrettyPrint(), true
+	case fmt.Stringer:
+		return x.String(), true
+	case error:
+		return x.Error(), true
+	}
+	return "", false
+}
+
+// writeTable writes t with box-drawing borders, or ASCII ones when
+// Config.ASCII is set. Lines after the first start at the current
+// column, so a table can follow a field name.
+func (f *formatter) writeTable(t *table) {
+	out := f.sb.String()
+	margin := "\n" + strings.Repeat(" ", displayWidth(stripANSI(out[strings.LastIndexByte(out, '\n')+1:])))
+
+	widths := make([]int, len(t.headers))
+	for i, h := range t.headers {
+		widths[i] = displayWidth(h)
+	}
+	for _, row := range t.rows {
+		for i, c := range row {
+			if w := displayWidth(c.text); w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	box := boxChars
+	if f.config.ASCII {
+		box = asciiBoxChars
+	}
+	rule := func(left, mid, right string) {
+		var sb strings.Builder
+		sb.WriteString(left)
+		for i, w := range widths {
+			if i > 0 {
+				sb.WriteString(mid)
+			}
+			sb.WriteString(strings.Repeat(box.horizontal, w+2))
+		}
+		sb.WriteString(right)
+		f.colored(cType, sb.String())
+	}
+	line := func(cells []cell) {
+		f.sb.WriteString(margin)
+		for i, c := range cells {
+			f.colored(cType, box.vertical)
+			f.sb.WriteString(" ")
+			pad := strings.Repeat(" ", widths[i]-displayWidth(c.text))
+			if t.numeric[i] {
+				f.sb.WriteString(pad)
+			}
+			if c.color != "" {
+				f.colored(c.color, c.text)
+			} else {
+				f.sb.WriteString(c.text)
+			}
+			if !t.numeric[i] {
+				f.sb.WriteString(pad)
+			}
+			f.sb.WriteString(" ")
+		}
+		f.colored(cType, box.vertical)
+	}
+
+	rule(box.topLeft, box.topMid, box.topRight)
+	headers := make([]cell, len(t.headers))
+	for i, h := range t.headers {
+		headers[i] = cell{text: h, color: cKey}
+	}
+	line(headers)
+	f.sb.WriteString(margin)
+	rule(box.midLeft, box.cross, box.midRight)
+	for _, row := range t.rows {
+		line(row)
+	}
+	f.sb.WriteString(margin)
+	rule(box.bottomLeft, box.bottomMid, box.bottomRight)
+}
+
+// boxSet holds the characters a table border is drawn with.
+type boxSet struct {
+	horizontal, vertical               string
+	topLeft, topMid, topRight          string
+	midLeft, cross, midRight           string
+	bottomLeft, bottomMid, bottomRight string
+}
+
+var (
+	boxChars      = boxSet{"─", "│", "┌", "┬", "┐", "├", "┼", "┤", "└", "┴", "┘"}
+	asciiBoxChars = boxSet{"-", "|", "+", "+", "+", "+", "+", "+", "+", "+", "+"}
+)
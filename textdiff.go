@@ -0,0 +1,237 @@
+package pf
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// editOp identifies a step in an edit script.
+type editOp int
+
+const (
+	opEqual editOp = iota
+	opDelete
+	opInsert
+)
+
+// edit is a single token in an edit script between two token sequences.
+type edit struct {
+	op   editOp
+	text string
+}
+
+// diffTokens returns the shortest edit script turning a into b, found
+// with Myers' O(ND) algorithm in its linear space form, so that long
+// texts with changes far apart do not need a table of all token pairs.
+func diffTokens(a, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	return appendDiff(edits, a, b)
+}
+
+// appendDiff appends the edit script turning a into b to edits. It
+// splits the problem at the middle snake of an optimal path and diffs
+// the parts before and after it.
+func appendDiff(edits []edit, a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, t := range a[:prefix] {
+		edits = append(edits, edit{opEqual, t})
+	}
+
+	ma := a[prefix : len(a)-suffix]
+	mb := b[prefix : len(b)-suffix]
+	switch {
+	case len(ma) == 0:
+		for _, t := range mb {
+			edits = append(edits, edit{opInsert, t})
+		}
+	case len(mb) == 0:
+		for _, t := range ma {
+			edits = append(edits, edit{opDelete, t})
+		}
+	default:
+		// Both parts differ at their ends, so each side of the snake
+		// costs at least one edit and the recursion makes progress.
+		x, y, u, v := middleSnake(ma, mb)
+		edits = appendDiff(edits, ma[:x], mb[:y])
+		for _, t := range ma[x:u] {
+			edits = append(edits, edit{opEqual, t})
+		}
+		edits = appendDiff(edits, ma[u:], mb[v:])
+	}
+
+	for _, t := range a[len(a)-suffix:] {
+		edits = append(edits, edit{opEqual, t})
+	}
+	return edits
+}
+
+// middleSnake returns the middle snake of a shortest edit path from a
+// to b, the run of equal tokens from (x, y) to (u, v), by searching
+// forward from the start and backward from the end until the paths
+// meet. a and b must not be empty.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	off := max + 1
+	// fwd[off+k] is the furthest x reached on diagonal k = x-y from the
+	// start; bwd[off+k] the furthest reached from the end, counting
+	// tokens back from it.
+	fwd := make([]int, 2*max+3)
+	bwd := make([]int, 2*max+3)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			x := fwd[off+k-1] + 1
+			if k == -d || k != d && fwd[off+k-1] < fwd[off+k+1] {
+				x = fwd[off+k+1]
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			fwd[off+k] = x
+			if r := delta - k; odd && r >= -(d-1) && r <= d-1 && x+bwd[off+r] >= n {
+				return x0, y0, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			x := bwd[off+k-1] + 1
+			if k == -d || k != d && bwd[off+k-1] < bwd[off+k+1] {
+				x = bwd[off+k+1]
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			bwd[off+k] = x
+			if f := delta - k; !odd && f >= -d && f <= d && x+fwd[off+f] >= n {
+				return n - x, m - y, n - x0, m - y0
+			}
+		}
+	}
+	panic("pf: no middle snake")
+}
+
+// splitWords splits s into runs of word characters, runs of whitespace,
+// and single punctuation runes. Concatenating the result yields s.
+func splitWords(s string) []string {
+	var tokens []string
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		n := size
+		switch {
+		case isWordRune(r):
+			for n < len(s) {
+				r2, s2 := utf8.DecodeRuneInString(s[n:])
+				if !isWordRune(r2) {
+					break
+				}
+				n += s2
+			}
+		case unicode.IsSpace(r):
+			for n < len(s) {
+				r2, s2 := utf8.DecodeRuneInString(s[n:])
+				if !unicode.IsSpace(r2) {
+					break
+				}
+				n += s2
+			}
+		}
+		tokens = append(tokens, s[:n])
+		s = s[n:]
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// splitLines splits s into lines without their trailing newlines.
+func splitLines(s string) []string {
+	return strings.Split(s, "\n")
+}
+
+// hunk is a group of nearby line edits with surrounding context.
+type hunk struct {
+	aStart, aLen int
+	bStart, bLen int
+	edits        []edit
+}
+
+// unifiedHunks groups a line edit script into hunks, keeping up to
+// context unchanged lines around each change.
+func unifiedHunks(edits []edit, context int) []hunk {
+	var hunks []hunk
+	aLine, bLine := 0, 0
+	// aPos/bPos hold the line numbers at the start of each edit.
+	aPos := make([]int, len(edits)+1)
+	bPos := make([]int, len(edits)+1)
+	for i, e := range edits {
+		aPos[i], bPos[i] = aLine, bLine
+		if e.op != opInsert {
+			aLine++
+		}
+		if e.op != opDelete {
+			bLine++
+		}
+	}
+	aPos[len(edits)], bPos[len(edits)] = aLine, bLine
+
+	i := 0
+	for i < len(edits) {
+		if edits[i].op == opEqual {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].op != opEqual {
+				end++
+				continue
+			}
+			// Look ahead: merge with the next change if the run of
+			// equal lines is short enough to be shared context.
+			run := end
+			for run < len(edits) && edits[run].op == opEqual {
+				run++
+			}
+			if run < len(edits) && run-end <= 2*context {
+				end = run
+				continue
+			}
+			end += context
+			if end > run {
+				end = run
+			}
+			break
+		}
+		hunks = append(hunks, hunk{
+			aStart: aPos[start],
+			aLen:   aPos[end] - aPos[start],
+			bStart: bPos[start],
+			bLen:   bPos[end] - bPos[start],
+			edits:  edits[start:end],
+		})
+		i = end
+	}
+	return hunks
+}
@@ -0,0 +1,97 @@
+package pf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Theme sets the colors used with ColorMode. Each field is a list of
+// SGR parameters, e.g. "34" for blue, "1;34" for bold blue or
+// "38;5;208" for color 208 of the 256-color palette. Empty fields keep
+// the default colors.
+type Theme struct {
+	Key, String, Number, Bool, Type, Nil, Brace string
+	// Deleted and Added color removed and added entries in diffs. The
+	// changed words within them are shown in reverse video.
+	Deleted, Added string
+}
+
+// builtinThemes are the themes PF_THEME and the config file can name.
+var builtinThemes = map[string]Theme{
+	"default": {},
+	// light suits terminals with a light background, where the default
+	// white braces and yellow numbers are hard to read.
+	"light": {Key: "34", String: "32", Number: "38;5;130", Bool: "35", Type: "90", Nil: "31", Brace: "30", Deleted: "31", Added: "32"},
+	"high-contrast": {
+		Key: "1;96", String: "92", Number: "93", Bool: "95", Type: "37", Nil: "91", Brace: "97", Deleted: "1;91", Added: "1;92",
+	},
+}
+
+// sgr returns the escape sequence for color, one of the color
+// constants, in this theme. In diffs, red and green are deletions and
+// additions rather than nil values and strings.
+func (t Theme) sgr(color string, diff bool) string {
+	var custom string
+	switch color {
+	case cKey:
+		custom = t.Key
+	case cNumber:
+		custom = t.Number
+	case cBool:
+		custom = t.Bool
+	case cType:
+		custom = t.Type
+	case cBrace:
+		custom = t.Brace
+	case cNil: // also cDiffDel
+		custom = t.Nil
+		if diff {
+			custom = t.Deleted
+		}
+	case cString: // also cDiffAdd
+		custom = t.String
+		if diff {
+			custom = t.Added
+		}
+	case cDiffDelHi:
+		if t.Deleted != "" {
+			custom = "7;" + t.Deleted
+		}
+	case cDiffAddHi:
+		if t.Added != "" {
+			custom = "7;" + t.Added
+		}
+	}
+	if custom == "" {
+		return color
+	}
+	return "\033[" + custom + "m"
+}
+
+// colorNames maps the words parseColor accepts to SGR parameters.
+var colorNames = map[string]string{
+	"bold": "1", "dim": "2", "italic": "3", "underline": "4",
+	"black": "30", "red": "31", "green": "32", "yellow": "33",
+	"blue": "34", "magenta": "35", "cyan": "36", "white": "37",
+	"gray": "90", "grey": "90",
+	"bright-red": "91", "bright-green": "92", "bright-yellow": "93",
+	"bright-blue": "94", "bright-magenta": "95", "bright-cyan": "96",
+	"bright-white": "97",
+}
+
+// parseColor converts a color such as "bold blue", "bright-red" or
+// "38;5;208" to SGR parameters for a Theme.
+func parseColor(s string) (string, error) {
+	var params []string
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		if p, ok := colorNames[word]; ok {
+			params = append(params, p)
+			continue
+		}
+		if strings.Trim(word, "0123456789;") != "" {
+			return "", fmt.Errorf("unknown color %q", word)
+		}
+		params = append(params, strings.Trim(word, ";"))
+	}
+	return strings.Join(params, ";"), nil
+}
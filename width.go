@@ -0,0 +1,86 @@
+package pf
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nd-forge/pf/internal/term"
+)
+
+// defaultWidth is the line width used when Config.Width is 0 and the
+// terminal size cannot be determined.
+const defaultWidth = 120
+
+// width returns the maximum line width for renderers that need one:
+// Config.Width if set, otherwise the width of the terminal on stdout,
+// then $COLUMNS, then defaultWidth.
+func (c Config) width() int {
+	if c.Width > 0 {
+		return c.Width
+	}
+	if cols, _, ok := term.Size(os.Stdout.Fd()); ok && cols > 0 {
+		return cols
+	}
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	return defaultWidth
+}
+
+// displayWidth returns the number of terminal cells s occupies,
+// counting East Asian wide characters as two cells.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F, // CJK ... Yi
+		r >= 0xAC00 && r <= 0xD7A3,                // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF,                // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F,                // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60,                // fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F, // emoji
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
+
+// truncateWidth shortens s to at most w cells, marking the cut with "…".
+func truncateWidth(s string, w int) string {
+	if displayWidth(s) <= w {
+		return s
+	}
+	if w <= 0 {
+		return ""
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range s {
+		rw := runeWidth(r)
+		if n+rw > w-1 {
+			break
+		}
+		sb.WriteRune(r)
+		n += rw
+	}
+	sb.WriteString("…")
+	return sb.String()
+}
+
+// padRight pads s with spaces to w cells.
+func padRight(s string, w int) string {
+	if n := displayWidth(s); n < w {
+		return s + strings.Repeat(" ", w-n)
+	}
+	return s
+}
@@ -0,0 +1,65 @@
+package pf
+
+import "fmt"
+
+// Wrapper formats a value with pf when used with the fmt verbs %v and
+// %s. See Wrap.
+type Wrapper struct {
+	value  interface{}
+	config *Config
+}
+
+// Wrap returns v wrapped so that fmt.Printf, log.Printf, fmt.Errorf and
+// friends format it with the default config, without colors since the output
+// usually ends up in logs and error messages:
+//
+//	%v, %s  pretty-printed
+//	%+v     with type names (ShowTypes)
+//	%#v     as Go syntax (GoSyntax)
+//	%80v    with a maximum line width of 80 (Width)
+//
+// Other verbs format the wrapped value as fmt would.
+//
+//	log.Printf("request: %v", pf.Wrap(req))
+func Wrap(v interface{}) Wrapper {
+	return Wrapper{value: v}
+}
+
+// Wrap returns v wrapped so that fmt verbs format it with this config,
+// colors included.
+func (c Config) Wrap(v interface{}) Wrapper {
+	return Wrapper{value: v, config: &c}
+}
+
+// Format implements fmt.Formatter.
+func (w Wrapper) Format(s fmt.State, verb rune) {
+	if verb != 'v' && verb != 's' {
+		fmt.Fprintf(s, fmt.FormatString(s, verb), w.value)
+		return
+	}
+	c := w.cfg()
+	if s.Flag('+') {
+		c.ShowTypes = true
+	}
+	if s.Flag('#') {
+		c.GoSyntax = true
+	}
+	if width, ok := s.Width(); ok {
+		c.Width = width
+	}
+	fmt.Fprint(s, c.Sprint(w.value))
+}
+
+// String implements fmt.Stringer.
+func (w Wrapper) String() string {
+	return w.cfg().Sprint(w.value)
+}
+
+func (w Wrapper) cfg() Config {
+	if w.config != nil {
+		return *w.config
+	}
+	c := Default()
+	c.ColorMode = false
+	return c
+}